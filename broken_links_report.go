@@ -0,0 +1,87 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+)
+
+const filenameBrokenLinksCSV = "broken_links.csv"
+
+// brokenLink is a single link from a crawled page to a URL that failed.
+type brokenLink struct {
+	TargetURL     string
+	StatusCode    int
+	ErrorCategory string
+	FetchError    string
+	SourceURL     string
+	AnchorText    string
+}
+
+// isBroken reports whether fetching a page failed in a way a visitor would hit:
+// an error status or the server not answering. Non-HTML files and pages we
+// chose not to fetch aren't broken.
+func isBroken(page PageData) bool {
+	if page.StatusCode >= 400 {
+		return true
+	}
+	switch page.ErrorCategory {
+	case "", errorCategoryNotHTML, errorCategoryCanceled:
+		return false
+	}
+	return true
+}
+
+// brokenLinks lists every link pointing at a broken page, crawled or external,
+// sorted by target and then by source page.
+func (cfg *config) brokenLinks() []brokenLink {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	result := []brokenLink{}
+	// Crawled pages are keyed by normalized URL, external ones by externalKey
+	targets := []struct {
+		pages   map[string]PageData
+		inbound map[string][]int
+	}{
+		{pages: cfg.pages, inbound: cfg.links.inbound},
+		{pages: cfg.externalPages, inbound: cfg.links.inboundByURL()},
+	}
+	for _, target := range targets {
+		for key, page := range target.pages {
+			if !isBroken(page) {
+				continue
+			}
+			for _, i := range target.inbound[key] {
+				edge := cfg.links.edges[i]
+				result = append(result, brokenLink{
+					TargetURL:     page.URL,
+					StatusCode:    page.StatusCode,
+					ErrorCategory: page.ErrorCategory,
+					FetchError:    page.FetchError,
+					SourceURL:     cfg.pageURL(edge.Source),
+					AnchorText:    edge.AnchorText,
+				})
+			}
+		}
+	}
+
+	slices.SortFunc(result, func(a, b brokenLink) int {
+		return cmp.Or(cmp.Compare(a.TargetURL, b.TargetURL), cmp.Compare(a.SourceURL, b.SourceURL))
+	})
+	return result
+}
+
+func writeBrokenLinksReport(links []brokenLink, filename string) error {
+	rows := [][]string{}
+	for _, link := range links {
+		rows = append(rows, []string{
+			link.TargetURL,
+			formatStatusCode(link.StatusCode),
+			link.ErrorCategory,
+			link.FetchError,
+			link.SourceURL,
+			link.AnchorText,
+		})
+	}
+	return writeCSV(filename, []string{"target_url", "status_code", "error_category", "error", "source_page_url", "anchor_text"}, rows)
+}
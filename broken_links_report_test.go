@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBrokenLinksReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/", "/about":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>
+				<a href="/about">About us</a>
+				<a href="/gone">Old post</a>
+				<a href="/broken">Status</a>
+				<a href="/file.pdf">Download</a>
+			</body></html>`)
+		case "/file.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+		case "/broken":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	settings := testFetcherSettings()
+	settings.maxRetries = 0
+	cfg, err := configure(server.URL, 2, 100, -1, true, settings)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg.enqueue(server.URL+"/", discoveredByLink, 0)
+	cfg.crawl(context.Background())
+
+	expected := []brokenLink{}
+	for _, target := range []string{"/broken", "/gone"} {
+		for _, source := range []string{"/", "/about"} {
+			link := brokenLink{
+				TargetURL:     server.URL + target,
+				StatusCode:    http.StatusNotFound,
+				ErrorCategory: errorCategoryHTTP4xx,
+				FetchError:    fmt.Sprintf("error (404) getting %s%s", server.URL, target),
+				SourceURL:     server.URL + source,
+				AnchorText:    "Old post",
+			}
+			if target == "/broken" {
+				link.StatusCode = http.StatusServiceUnavailable
+				link.ErrorCategory = errorCategoryHTTP5xx
+				link.FetchError = fmt.Sprintf("error (503) getting %s%s", server.URL, target)
+				link.AnchorText = "Status"
+			}
+			expected = append(expected, link)
+		}
+	}
+
+	actual := cfg.brokenLinks()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %+v\nActual: %+v", expected, actual)
+	}
+
+	filename := filepath.Join(t.TempDir(), filenameBrokenLinksCSV)
+	if err := writeBrokenLinksReport(actual, filename); err != nil {
+		t.Fatalf("Unexpected error writing report: %v", err)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	rows, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("Unexpected error reading report: %v", err)
+	}
+	if len(rows) != len(expected)+1 {
+		t.Errorf("Expected %d rows including the header, got %d", len(expected)+1, len(rows))
+	}
+}
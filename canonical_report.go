@@ -0,0 +1,97 @@
+package main
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+)
+
+const filenameCanonicalsCSV = "canonical_mismatches.csv"
+
+// Ways a page's canonical URL can disagree with the page, from the most to the
+// least serious.
+const (
+	canonicalIssueOtherSite       = "other_site"
+	canonicalIssueTargetBroken    = "target_broken"
+	canonicalIssueTargetRedirects = "target_redirects"
+	canonicalIssueTargetNoindex   = "target_noindex"
+	canonicalIssueOtherPage       = "other_page"
+)
+
+const duplicateCanonicalReason = "duplicate of canonical "
+
+// canonicalMismatch is a page whose <link rel="canonical"> isn't itself.
+type canonicalMismatch struct {
+	PageURL      string
+	CanonicalURL string
+	Issue        string
+}
+
+// canonicalDuplicateOf returns the normalized canonical URL of a page if it
+// names another page on the site, and false otherwise.
+func (cfg *config) canonicalDuplicateOf(normalizedURL, canonical string) (string, bool) {
+	if canonical == "" {
+		return "", false
+	}
+	canonicalURL, err := url.Parse(canonical)
+	if err != nil || !cfg.isSameSite(canonicalURL) {
+		return "", false
+	}
+	normalizedCanonical, err := cfg.normalize(canonical)
+	if err != nil || normalizedCanonical == normalizedURL {
+		return "", false
+	}
+	return normalizedCanonical, true
+}
+
+// canonicalMismatches lists the crawled pages whose canonical URL points away
+// from them, sorted by page URL.
+func (cfg *config) canonicalMismatches() []canonicalMismatch {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	result := []canonicalMismatch{}
+	for normalizedURL, page := range cfg.pages {
+		if page.Canonical == "" {
+			continue
+		}
+		issue := ""
+		canonicalURL, err := url.Parse(page.Canonical)
+		if err != nil || !cfg.isSameSite(canonicalURL) {
+			issue = canonicalIssueOtherSite
+		} else if normalizedCanonical, ok := cfg.canonicalDuplicateOf(normalizedURL, page.Canonical); ok {
+			issue = canonicalIssueOtherPage
+			if target, crawled := cfg.pages[normalizedCanonical]; crawled {
+				switch {
+				case isBroken(target):
+					issue = canonicalIssueTargetBroken
+				case len(target.Redirects) > 0:
+					issue = canonicalIssueTargetRedirects
+				case target.NoIndex:
+					issue = canonicalIssueTargetNoindex
+				}
+			}
+		}
+		if issue == "" {
+			continue
+		}
+		result = append(result, canonicalMismatch{PageURL: page.URL, CanonicalURL: page.Canonical, Issue: issue})
+	}
+
+	slices.SortFunc(result, func(a, b canonicalMismatch) int {
+		return cmp.Compare(a.PageURL, b.PageURL)
+	})
+	return result
+}
+
+func writeCanonicalsReport(mismatches []canonicalMismatch, filename string) error {
+	rows := [][]string{}
+	for _, mismatch := range mismatches {
+		rows = append(rows, []string{
+			mismatch.PageURL,
+			mismatch.CanonicalURL,
+			mismatch.Issue,
+		})
+	}
+	return writeCSV(filename, []string{"page_url", "canonical_url", "issue"}, rows)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestDedupeByCanonical(t *testing.T) {
+	var mu sync.Mutex
+	requested := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body>
+				<a href="/shoes-red">Red shoes</a>
+				<a href="/moved-copy">Moved</a>
+				<a href="/elsewhere">Elsewhere</a>
+			</body></html>`)
+		case "/shoes-red":
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="/shoes"></head><body><a href="/shoes-red/reviews">Reviews</a></body></html>`)
+		case "/moved-copy":
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="/old"></head><body></body></html>`)
+		case "/old":
+			http.Redirect(w, r, "/", http.StatusMovedPermanently)
+		case "/elsewhere":
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="https://example.com/elsewhere"></head><body></body></html>`)
+		default:
+			fmt.Fprintf(w, `<html><head><link rel="canonical" href="%s"></head><body></body></html>`, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	cfg, err := configure(server.URL, 1, 100, -1, true, testFetcherSettings())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg.dedupeCanonical = true
+	cfg.enqueue(server.URL+"/", discoveredByLink, 0)
+	cfg.crawl(context.Background())
+
+	mu.Lock()
+	crawled := slices.Clone(requested)
+	mu.Unlock()
+	slices.Sort(crawled)
+	// /shoes-red/reviews isn't crawled: only the canonical page's links are followed
+	if expected := []string{"/", "/", "/elsewhere", "/moved-copy", "/old", "/shoes", "/shoes-red"}; !reflect.DeepEqual(crawled, expected) {
+		t.Errorf("Expected crawled: %v\nActual: %v", expected, crawled)
+	}
+	if reason := cfg.pages[cfg.baseURL.Hostname()+"/shoes-red"].SkipReason; reason != duplicateCanonicalReason+server.URL+"/shoes" {
+		t.Errorf("Expected /shoes-red to be marked as a duplicate, got %q", reason)
+	}
+
+	expected := []canonicalMismatch{
+		{PageURL: server.URL + "/elsewhere", CanonicalURL: "https://example.com/elsewhere", Issue: canonicalIssueOtherSite},
+		{PageURL: server.URL + "/moved-copy", CanonicalURL: server.URL + "/old", Issue: canonicalIssueTargetRedirects},
+		{PageURL: server.URL + "/shoes-red", CanonicalURL: server.URL + "/shoes", Issue: canonicalIssueOtherPage},
+	}
+	if actual := cfg.canonicalMismatches(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %+v\nActual: %+v", expected, actual)
+	}
+}
+
+func TestDedupeByCanonicalLimits(t *testing.T) {
+	tests := []struct {
+		name              string
+		seedPath          string
+		maxDepth          int
+		obeyNofollow      bool
+		expectedRequested []string
+	}{
+		{
+			name:              "canonical page is crawled instead of the copy",
+			seedPath:          "/copy",
+			maxDepth:          -1,
+			expectedRequested: []string{"/copy", "/original"},
+		},
+		{
+			name:              "not past the max depth",
+			seedPath:          "/copy",
+			maxDepth:          0,
+			expectedRequested: []string{"/copy"},
+		},
+		{
+			name:              "not from a nofollow page",
+			seedPath:          "/nofollow-copy",
+			maxDepth:          -1,
+			obeyNofollow:      true,
+			expectedRequested: []string{"/nofollow-copy"},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, requested := newRecordingServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				switch r.URL.Path {
+				case "/copy":
+					fmt.Fprint(w, `<html><head><link rel="canonical" href="/original"></head><body></body></html>`)
+				case "/nofollow-copy":
+					fmt.Fprint(w, `<html><head><link rel="canonical" href="/original"><meta name="robots" content="nofollow"></head><body></body></html>`)
+				default:
+					fmt.Fprint(w, `<html><body></body></html>`)
+				}
+			})
+
+			cfg, err := configure(server.URL, 1, 100, tc.maxDepth, true, testFetcherSettings())
+			if err != nil {
+				t.Fatalf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+			}
+			cfg.dedupeCanonical = true
+			cfg.obeyNofollow = tc.obeyNofollow
+			cfg.enqueue(server.URL+tc.seedPath, discoveredByLink, 0)
+			cfg.crawl(context.Background())
+
+			if actual := requested(); !reflect.DeepEqual(tc.expectedRequested, actual) {
+				t.Errorf("Test %v - %s\nExpected requests: %v\nActual: %v", i+1, tc.name, tc.expectedRequested, actual)
+			}
+		})
+	}
+}
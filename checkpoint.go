@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type checkpointItem struct {
+	URL           string `json:"url"`
+	NormalizedURL string `json:"normalized_url"`
+	Source        string `json:"source"`
+	Depth         int    `json:"depth"`
+}
+
+// checkpoint is everything needed to pick a crawl back up where it left off.
+type checkpoint struct {
+	BaseURL      string              `json:"base_url"`
+	SavedAt      time.Time           `json:"saved_at"`
+	Pages        map[string]PageData `json:"pages"`
+	Frontier     []checkpointItem    `json:"frontier"`
+	SitemapSeeds []checkpointItem    `json:"sitemap_seeds"`
+	Links        []linkEdge          `json:"links"`
+	Excluded     map[string]PageData `json:"excluded,omitempty"`
+	Traps        map[string]trapURL  `json:"traps,omitempty"`
+}
+
+func toCheckpointItems(items []frontierItem) []checkpointItem {
+	result := make([]checkpointItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, checkpointItem{
+			URL:           item.rawURL,
+			NormalizedURL: item.normalizedURL,
+			Source:        item.source,
+			Depth:         item.depth,
+		})
+	}
+	return result
+}
+
+func fromCheckpointItems(items []checkpointItem) []frontierItem {
+	result := make([]frontierItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, frontierItem{
+			rawURL:        item.URL,
+			normalizedURL: item.NormalizedURL,
+			source:        item.Source,
+			depth:         item.Depth,
+		})
+	}
+	return result
+}
+
+// snapshot captures the crawl state. Pages still being crawled are saved as if
+// they were never taken from the frontier, along with none of their links, so a
+// resumed crawl fetches them again from scratch.
+func (cfg *config) snapshot() checkpoint {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	pages := make(map[string]PageData, len(cfg.pages))
+	for normalizedURL, page := range cfg.pages {
+		pages[normalizedURL] = page
+	}
+
+	pending := []frontierItem{}
+	for _, item := range cfg.active {
+		page := pages[item.normalizedURL]
+		pages[item.normalizedURL] = PageData{URL: item.rawURL, DiscoveredBy: page.DiscoveredBy, Depth: page.Depth}
+		pending = append(pending, item)
+	}
+	pending = append(pending, cfg.frontier...)
+
+	excluded := make(map[string]PageData, len(cfg.excluded))
+	for normalizedURL, page := range cfg.excluded {
+		excluded[normalizedURL] = page
+	}
+
+	traps := make(map[string]trapURL, len(cfg.traps.found))
+	for normalizedURL, trap := range cfg.traps.found {
+		traps[normalizedURL] = trap
+	}
+
+	links := []linkEdge{}
+	for _, edge := range cfg.links.edges {
+		if _, inFlight := cfg.active[edge.Source]; !inFlight {
+			links = append(links, edge)
+		}
+	}
+
+	return checkpoint{
+		BaseURL:      cfg.baseURL.String(),
+		SavedAt:      time.Now(),
+		Pages:        pages,
+		Frontier:     toCheckpointItems(pending),
+		SitemapSeeds: toCheckpointItems(cfg.sitemapSeeds),
+		Links:        links,
+		Excluded:     excluded,
+		Traps:        traps,
+	}
+}
+
+// saveCheckpoint writes the crawl state to cfg.checkpointFile. The file is
+// replaced atomically so a crash mid-write never leaves a corrupt checkpoint.
+func (cfg *config) saveCheckpoint() error {
+	data, err := json.Marshal(cfg.snapshot())
+	if err != nil {
+		return fmt.Errorf("couldn't encode checkpoint: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(cfg.checkpointFile), filepath.Base(cfg.checkpointFile)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cfg.checkpointFile)
+}
+
+// startCheckpoints saves a checkpoint every cfg.checkpointInterval until the
+// returned function is called.
+func (cfg *config) startCheckpoints() (stop func()) {
+	ticker := time.NewTicker(cfg.checkpointInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if err := cfg.saveCheckpoint(); err != nil {
+					fmt.Printf("Error - saveCheckpoint: %v\n", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// loadCheckpoint restores the state saved by saveCheckpoint, so calling crawl
+// continues with only the URLs that weren't crawled yet.
+func (cfg *config) loadCheckpoint(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var state checkpoint
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("couldn't decode checkpoint: %v", err)
+	}
+	if state.BaseURL != cfg.baseURL.String() {
+		return fmt.Errorf("checkpoint is for %s, not %s", state.BaseURL, cfg.baseURL.String())
+	}
+
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	cfg.pages = state.Pages
+	if cfg.pages == nil {
+		cfg.pages = make(map[string]PageData)
+	}
+	cfg.excluded = state.Excluded
+	if cfg.excluded == nil {
+		cfg.excluded = make(map[string]PageData)
+	}
+	cfg.traps = newTrapDetector(cfg.traps.settings)
+	for normalizedURL := range cfg.pages {
+		cfg.traps.observe(normalizedURL)
+	}
+	if state.Traps != nil {
+		cfg.traps.found = state.Traps
+	}
+	cfg.frontier = fromCheckpointItems(state.Frontier)
+	cfg.sitemapSeeds = fromCheckpointItems(state.SitemapSeeds)
+	// Pages an earlier run marked as interrupted are crawled after all
+	for _, item := range cfg.frontier {
+		page := cfg.pages[item.normalizedURL]
+		page.SkipReason = ""
+		cfg.pages[item.normalizedURL] = page
+	}
+	cfg.links = newLinkGraph()
+	for _, edge := range state.Links {
+		cfg.links.addEdge(edge)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	links := map[string][]string{
+		"/":  {"/a", "/b"},
+		"/a": {"/a1", "/a2", "/"},
+		"/b": {"/b1", "/b2"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var interrupting atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if interrupting.Load() && r.URL.Path == "/a" {
+			// Simulate the crawl being killed while this page is being fetched
+			cancel()
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>")
+		for _, link := range links[r.URL.Path] {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, link)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	defer server.Close()
+
+	// An uninterrupted crawl to compare against
+	expected, err := configure(server.URL, 1, 100, -1, true, testFetcherSettings())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected.enqueue(server.URL+"/", discoveredByLink, 0)
+	expected.crawl(context.Background())
+	expected.applyLinkGraph()
+
+	checkpointFile := filepath.Join(t.TempDir(), "state.json")
+	first, err := configure(server.URL, 1, 100, -1, true, testFetcherSettings())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	first.gracePeriod = 10 * time.Millisecond
+	first.checkpointFile = checkpointFile
+	first.checkpointInterval = time.Hour
+	first.enqueue(server.URL+"/", discoveredByLink, 0)
+	interrupting.Store(true)
+	if first.crawl(ctx) {
+		t.Fatalf("Expected the first crawl to be interrupted")
+	}
+	interrupting.Store(false)
+
+	resumed, err := configure(server.URL, 1, 100, -1, true, testFetcherSettings())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := resumed.loadCheckpoint(checkpointFile); err != nil {
+		t.Fatalf("Unexpected error loading checkpoint: %v", err)
+	}
+	if len(resumed.frontier) != 2 {
+		t.Errorf("Expected 2 URLs left to crawl, got %d", len(resumed.frontier))
+	}
+	if !resumed.crawl(context.Background()) {
+		t.Fatalf("Expected the resumed crawl to complete")
+	}
+	resumed.applyLinkGraph()
+
+	// Response times naturally differ between runs
+	for _, cfg := range []*config{expected, resumed} {
+		for normalizedURL, page := range cfg.pages {
+			page.ResponseTime = 0
+			cfg.pages[normalizedURL] = page
+		}
+	}
+	if !reflect.DeepEqual(resumed.pages, expected.pages) {
+		t.Errorf("Resumed crawl differs from uninterrupted crawl\nExpected: %+v\nActual: %+v", expected.pages, resumed.pages)
+	}
+	if len(resumed.links.edges) != len(expected.links.edges) {
+		t.Errorf("Expected %d links, got %d", len(expected.links.edges), len(resumed.links.edges))
+	}
+
+	other, _ := configure("https://example.com", 1, 100, -1, true, testFetcherSettings())
+	if err := other.loadCheckpoint(checkpointFile); err == nil {
+		t.Errorf("Expected an error loading a checkpoint for another site")
+	}
+}
@@ -4,50 +4,120 @@ import (
 	"fmt"
 	"net/url"
 	"sync"
+	"time"
 )
 
 type config struct {
 	pages              map[string]PageData
+	externalPages      map[string]PageData
+	excluded           map[string]PageData
+	filter             urlFilter
+	traps              *trapDetector
 	baseURL            *url.URL
+	seeds              []*url.URL
+	scope              crawlScope
+	normalizeRules     normalizeRules
 	mu                 *sync.Mutex
-	concurrencyControl chan struct{}
+	frontier           []frontierItem
+	frontierCond       *sync.Cond
+	inFlight           int
+	inFlightDepths     map[int]int
+	active             map[string]frontierItem
+	sitemapSeeds       []frontierItem
+	stopped            bool
+	gracePeriod        time.Duration
+	maxRedirectChain   int
+	metaLimits         pageMetaLimits
+	checkpointFile     string
+	checkpointInterval time.Duration
 	wg                 *sync.WaitGroup
+	maxConcurrency     int
 	maxPages           int
+	maxDepth           int
+	links              *linkGraph
+	fetcher            *fetcher
+	robots             *robotsCache
+	ignoreRobots       bool
+	obeyNofollow       bool
+	dedupeCanonical    bool
+	extractors         []Extractor
 }
 
-// addPageVisit returns true if this is the first time we see the URL.
-// We insert a placeholder PageData to mark it as visited; it’ll be replaced later.
-func (cfg *config) addPageVisit(normalizedURL string) (isFirst bool) {
-	cfg.mu.Lock()
-	defer cfg.mu.Unlock()
+// How a page was found: through a link on another page, through a sitemap, or both.
+const (
+	discoveredByLink    = "link"
+	discoveredBySitemap = "sitemap"
+	discoveredByBoth    = "both"
+)
 
-	if _, visited := cfg.pages[normalizedURL]; visited {
-		return false
+func mergeDiscovery(current, source string) string {
+	if current == "" || current == source {
+		return source
 	}
-
-	cfg.pages[normalizedURL] = PageData{URL: normalizedURL}
-	return true
+	return discoveredByBoth
 }
 
-// setPageData safely stores the final PageData for a URL.
+// setPageData safely stores the final PageData for a URL, keeping how and at
+// which depth it was discovered.
 func (cfg *config) setPageData(normalizedURL string, data PageData) {
 	cfg.mu.Lock()
 	defer cfg.mu.Unlock()
+	data.DiscoveredBy = cfg.pages[normalizedURL].DiscoveredBy
+	data.Depth = cfg.pages[normalizedURL].Depth
 	cfg.pages[normalizedURL] = data
 }
 
-func configure(rawBaseURL string, maxConcurrency, maxPages int) (*config, error) {
-	baseURL, err := url.Parse(rawBaseURL)
+// pageURL returns the URL a crawled page was found at, or normalizedURL itself
+// for pages that weren't crawled. It must be called with cfg.mu held.
+func (cfg *config) pageURL(normalizedURL string) string {
+	if page, ok := cfg.pages[normalizedURL]; ok {
+		return page.URL
+	}
+	return normalizedURL
+}
+
+// normalize normalizes a URL with the crawl's normalization rules.
+func (cfg *config) normalize(rawURL string) (string, error) {
+	return cfg.normalizeRules.normalize(rawURL)
+}
+
+// isSameSite reports whether a URL is on the site being crawled, as set by the scope.
+func (cfg *config) isSameSite(u *url.URL) bool {
+	return cfg.scope.contains(u.Hostname(), cfg.normalizeRules)
+}
+
+func configure(rawBaseURL string, maxConcurrency, maxPages, maxDepth int, ignoreRobots bool, fetchSettings fetcherSettings) (*config, error) {
+	baseURL, err := parseSeedURL(rawBaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't parse base URL: %v", err)
+		return nil, fmt.Errorf("invalid base URL: %v", err)
 	}
 
+	mu := &sync.Mutex{}
+	f := newFetcher(fetchSettings)
 	return &config{
-		pages:              make(map[string]PageData),
-		baseURL:            baseURL,
-		mu:                 &sync.Mutex{},
-		concurrencyControl: make(chan struct{}, maxConcurrency),
-		wg:                 &sync.WaitGroup{},
-		maxPages:           maxPages,
+		pages:            make(map[string]PageData),
+		externalPages:    make(map[string]PageData),
+		excluded:         make(map[string]PageData),
+		traps:            newTrapDetector(defaultTrapSettings()),
+		extractors:       defaultExtractors(),
+		baseURL:          baseURL,
+		seeds:            []*url.URL{baseURL},
+		scope:            crawlScope{mode: scopeHost, hosts: []string{baseURL.Hostname()}},
+		normalizeRules:   defaultNormalizeRules(),
+		mu:               mu,
+		frontierCond:     sync.NewCond(mu),
+		inFlightDepths:   make(map[int]int),
+		active:           make(map[string]frontierItem),
+		wg:               &sync.WaitGroup{},
+		maxConcurrency:   max(maxConcurrency, 1),
+		maxPages:         maxPages,
+		maxDepth:         maxDepth,
+		gracePeriod:      10 * time.Second,
+		maxRedirectChain: defaultMaxRedirectChain,
+		metaLimits:       defaultPageMetaLimits(),
+		links:            newLinkGraph(),
+		fetcher:          f,
+		robots:           newRobotsCache(f),
+		ignoreRobots:     ignoreRobots,
 	}, nil
 }
@@ -1,66 +1,105 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/url"
 )
 
-func (cfg *config) crawlPage(rawCurrentURL string) {
-	cfg.concurrencyControl <- struct{}{}
-	defer func() {
-		<-cfg.concurrencyControl
-		cfg.wg.Done()
-	}()
-
-	// Stop if max pages reached
-	cfg.mu.Lock()
-	if len(cfg.pages) >= cfg.maxPages {
-		cfg.mu.Unlock()
+// crawlPage fetches a single page taken from the frontier, stores its data and
+// queues the links found on it.
+func (cfg *config) crawlPage(ctx context.Context, item frontierItem) {
+	currentURL, err := url.Parse(item.rawURL)
+	if err != nil {
+		fmt.Printf("Error - crawlPage: couldn't parse URL '%s': %v\n", item.rawURL, err)
 		return
 	}
-	cfg.mu.Unlock()
 
-	currentURL, err := url.Parse(rawCurrentURL)
-	if err != nil {
-		fmt.Printf("Error - crawlPage: couldn't parse URL '%s': %v\n", rawCurrentURL, err)
-		return
+	if !cfg.ignoreRobots {
+		allowed, err := cfg.robots.allowed(ctx, currentURL)
+		var unreachable *robotsUnreachableError
+		if errors.As(err, &unreachable) {
+			// Keep how the host failed, so the page shows up as broken rather than disallowed
+			cfg.setPageData(item.normalizedURL, PageData{URL: item.rawURL, SkipReason: robotsUnreachableReason, FetchRecord: unreachable.record})
+			return
+		}
+		if err != nil {
+			// Interrupted before robots.txt came in, so we don't know yet
+			cfg.requeue(item)
+			return
+		}
+		if !allowed {
+			cfg.setPageData(item.normalizedURL, PageData{URL: item.rawURL, SkipReason: robotsBlockedReason})
+			return
+		}
 	}
 
-	// stay within the same site
-	if currentURL.Hostname() != cfg.baseURL.Hostname() {
+	fmt.Printf("crawling %s\n", item.rawURL)
+
+	result, err := cfg.fetcher.getHTML(ctx, item.rawURL)
+	record := newFetchRecord(result, err)
+	if err != nil {
+		fmt.Printf("Error - getHTML: %v\n", err)
+		if ctx.Err() != nil {
+			cfg.requeue(item)
+			return
+		}
+		cfg.setPageData(item.normalizedURL, PageData{URL: item.rawURL, FetchRecord: record})
 		return
 	}
+	htmlBody := string(result.body)
+	// Relative links are relative to where we ended up after any redirects
+	finalURL := result.res.Request.URL
 
-	normalizedURL, err := normalizeURL(rawCurrentURL)
+	// Extract all the data we care about and store it
+	page, err := parsePage(htmlBody, finalURL.String())
 	if err != nil {
-		fmt.Printf("Error - normalizedURL: %v\n", err)
+		fmt.Printf("Error - parsePage: %v\n", err)
+		cfg.setPageData(item.normalizedURL, PageData{URL: item.rawURL, FetchRecord: record})
 		return
 	}
+	pageData := page.extract(cfg.extractors)
+	pageData.URL = item.rawURL
+	pageData.Truncated = result.truncated
+	pageData.FetchRecord = record
+	pageData.RobotsDirectives = pageData.RobotsDirectives.merge(parseXRobotsTag(result.res.Header.Values("X-Robots-Tag")))
+	_, duplicate := cfg.canonicalDuplicateOf(item.normalizedURL, pageData.Canonical)
+	duplicate = duplicate && cfg.dedupeCanonical
+	if duplicate {
+		pageData.SkipReason = duplicateCanonicalReason + pageData.Canonical
+	}
+	cfg.setPageData(item.normalizedURL, pageData)
 
-	// Only proceed the first time we see this normalized URL
-	isFirst := cfg.addPageVisit(normalizedURL)
-	if !isFirst {
-		updatedVisits := cfg.pages[normalizedURL]
-		updatedVisits.Visits++
-		cfg.pages[normalizedURL] = updatedVisits
+	links := page.Links()
+	cfg.recordLinks(item.normalizedURL, links)
+
+	// Don't follow links past the max depth. Without a known depth we can't tell
+	// how far we are, so links are only followed when there is no limit.
+	if cfg.maxDepth >= 0 && (item.depth == unknownDepth || item.depth >= cfg.maxDepth) {
 		return
 	}
+	nextDepth := unknownDepth
+	if item.depth != unknownDepth {
+		nextDepth = item.depth + 1
+	}
 
-	fmt.Printf("crawling %s\n", rawCurrentURL)
-
-	htmlBody, err := getHTML(rawCurrentURL)
-	if err != nil {
-		fmt.Printf("Error - getHTML: %v\n", err)
+	// The links are in the link graph either way, so nofollow links still show up in reports
+	if cfg.obeyNofollow && pageData.NoFollow {
 		return
 	}
 
-	// Extract all the data we care about and store it
-	pageData := extractPageData(htmlBody, rawCurrentURL)
-	cfg.setPageData(normalizedURL, pageData)
+	// A copy of another page has the same links, so crawl the canonical page instead
+	if duplicate {
+		cfg.enqueue(pageData.Canonical, discoveredByLink, item.depth)
+		return
+	}
 
-	// Recurse using the already-extracted outgoing links
-	for _, nextURL := range pageData.OutgoingLinks {
-		cfg.wg.Add(1)
-		go cfg.crawlPage(nextURL)
+	// Queue the already-extracted outgoing links
+	for _, link := range links {
+		if cfg.obeyNofollow && hasRel(link.Rel, "nofollow") {
+			continue
+		}
+		cfg.enqueue(link.URL, discoveredByLink, nextDepth)
 	}
 }
@@ -2,22 +2,89 @@ package main
 
 import (
 	"encoding/csv"
+	"errors"
+	"io"
+	"io/fs"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 )
 
-func writeCSVReport(pages map[string]PageData, filename string) error {
+// csvReportColumns are the built-in columns of the page report.
+var csvReportColumns = []string{
+	"page_url",
+	"h1",
+	"first_paragraph",
+	"outgoing_link_urls",
+	"image_urls",
+	"references",
+	"skip_reason",
+	"discovered_by",
+	"depth",
+	"inbound_links",
+	"outbound_links",
+	"truncated",
+	"fetch_attempts",
+	"fetch_error",
+	"status_code",
+	"final_url",
+	"redirect_chain",
+	"content_type",
+	"response_time_ms",
+	"bytes",
+	"error_category",
+	"noindex",
+	"nofollow",
+	"noarchive",
+	"canonical",
+	"title",
+	"title_length",
+	"meta_description",
+	"meta_description_length",
+	"meta_keywords",
+	"h1_count",
+	"h2_count",
+	"h3_count",
+	"h4_count",
+	"h5_count",
+	"h6_count",
+	"heading_outline",
+	"og_title",
+	"og_description",
+	"og_image",
+	"twitter_card",
+	"schema_types",
+	"jsonld_errors",
+}
+
+// filenameIncomplete sits next to the reports of an interrupted crawl, so partial
+// reports aren't mistaken for full ones.
+const filenameIncomplete = "INCOMPLETE"
+
+// writeIncompleteMarker creates filename if the crawl was interrupted and
+// removes one left over from an earlier run otherwise.
+func writeIncompleteMarker(filename string, complete bool) error {
+	if complete {
+		if err := os.Remove(filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(filename, []byte("the crawl was interrupted before every page was visited\n"), 0o644)
+}
+
+// writeCSVReport writes one row per page, followed by the fields extracted by
+// selector rules.
+func writeCSVReport(pages map[string]PageData, filename string, customFields []string) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
-	writer := csv.NewWriter(file)
-	writer.Comma = ';'
-
-	// Write headers
-	writer.Write([]string{"page_url", "h1", "first_paragraph", "outgoing_link_urls", "image_urls", "references"})
+	defer file.Close()
 
 	// For each page, write its data
+	rows := [][]string{}
 	for _, data := range pages {
 		record := []string{
 			data.URL,
@@ -25,12 +92,90 @@ func writeCSVReport(pages map[string]PageData, filename string) error {
 			data.FirstParagraph,
 			strings.Join(data.OutgoingLinks, ","),
 			strings.Join(data.ImageURLs, ","),
+			strings.Join(data.ReferringPages, ","),
+			data.SkipReason,
+			data.DiscoveredBy,
+			formatDepth(data.Depth),
+			strconv.Itoa(data.InboundLinks),
+			strconv.Itoa(data.OutboundLinks),
+			strconv.FormatBool(data.Truncated),
+			strconv.Itoa(data.FetchAttempts),
+			data.FetchError,
+			formatStatusCode(data.StatusCode),
+			data.FinalURL,
+			formatRedirects(data.Redirects, data.FinalURL),
+			data.ContentType,
+			strconv.FormatInt(data.ResponseTime.Milliseconds(), 10),
+			strconv.Itoa(data.ByteSize),
+			data.ErrorCategory,
+			strconv.FormatBool(data.NoIndex),
+			strconv.FormatBool(data.NoFollow),
+			strconv.FormatBool(data.NoArchive),
+			data.Canonical,
+			data.Title,
+			strconv.Itoa(textLength(data.Title)),
+			data.MetaDescription,
+			strconv.Itoa(textLength(data.MetaDescription)),
+			data.MetaKeywords,
+		}
+		for _, count := range data.headingCounts() {
+			record = append(record, strconv.Itoa(count))
+		}
+		record = append(record,
+			formatHeadingOutline(data.Headings),
+			data.OpenGraph["og:title"],
+			data.OpenGraph["og:description"],
+			data.OpenGraph["og:image"],
+			data.TwitterCard["twitter:card"],
+			strings.Join(data.SchemaTypes, ","),
+			strconv.Itoa(len(data.JSONLDErrors)),
+		)
+		for _, field := range customFields {
+			record = append(record, data.CustomFields[field])
 		}
-		err = writer.Write(record)
-		if err != nil {
+		rows = append(rows, record)
+	}
+	return writeCSVTo(file, append(slices.Clone(csvReportColumns), customFields...), rows)
+}
+
+// writeCSV writes a report of ';'-separated rows under a header line.
+func writeCSV(filename string, header []string, rows [][]string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return writeCSVTo(file, header, rows)
+}
+
+func writeCSVTo(w io.Writer, header []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+
+	// Write headers
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := writer.Write(row); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatStatusCode(statusCode int) string {
+	if statusCode == 0 {
+		return ""
+	}
+	return strconv.Itoa(statusCode)
+}
+
+func formatDepth(depth int) string {
+	if depth == unknownDepth {
+		return ""
+	}
+	return strconv.Itoa(depth)
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteCSVReport(t *testing.T) {
+	pages := map[string]PageData{
+		"example.com": {URL: "https://example.com", H1: "Home", CustomFields: map[string]string{"price": "10"}},
+	}
+	filename := filepath.Join(t.TempDir(), "report.csv")
+	if err := writeCSVReport(pages, filename, []string{"price"}); err != nil {
+		t.Fatalf("Unexpected error writing report: %v", err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	rows, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("Unexpected error reading report: %v", err)
+	}
+	expectedHeader := append(slices.Clone(csvReportColumns), "price")
+	if len(rows) != 2 || !slices.Equal(rows[0], expectedHeader) {
+		t.Fatalf("Expected a header and one row, got %v", rows)
+	}
+	if row := rows[1]; row[0] != "https://example.com" || row[1] != "Home" || row[len(row)-1] != "10" {
+		t.Errorf("Unexpected row: %v", row)
+	}
+}
+
+func TestWriteIncompleteMarker(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), filenameIncomplete)
+
+	if err := writeIncompleteMarker(filename, false); err != nil {
+		t.Fatalf("Unexpected error writing marker: %v", err)
+	}
+	if _, err := os.Stat(filename); err != nil {
+		t.Errorf("Expected an interrupted crawl to leave a marker: %v", err)
+	}
+
+	// A later complete run clears the marker, and clearing twice is fine
+	for range 2 {
+		if err := writeIncompleteMarker(filename, true); err != nil {
+			t.Fatalf("Unexpected error clearing marker: %v", err)
+		}
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("Expected a complete crawl to remove the marker, got %v", err)
+	}
+}
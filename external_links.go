@@ -0,0 +1,125 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+const filenameExternalLinksCSV = "external_links.csv"
+
+// maxExternalBodyBytes caps how much of an external page is read when a HEAD
+// request isn't supported. Only the status matters, so there's no point in more.
+const maxExternalBodyBytes = 64 * 1024
+
+// externalKey identifies an external URL: the link URL without its fragment.
+// The crawl's normalization would merge URLs that differ in scheme or query,
+// but on another site those can be different pages.
+func externalKey(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	parsedURL.Fragment = ""
+	parsedURL.RawFragment = ""
+	return parsedURL.String()
+}
+
+// externalTargets returns the distinct http(s) URLs on other sites that crawled
+// pages link to, keyed by externalKey.
+func (cfg *config) externalTargets() []string {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	targets := []string{}
+	for key := range cfg.links.inboundByURL() {
+		parsedURL, err := url.Parse(key)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+			continue
+		}
+		if cfg.isSameSite(parsedURL) {
+			continue
+		}
+		targets = append(targets, key)
+	}
+	slices.Sort(targets)
+	return targets
+}
+
+// checkExternalLinks requests every external link target once, without crawling
+// it, and stores how it went in cfg.externalPages. It uses its own fetcher so
+// external hosts get their own, more conservative, rate limit.
+func (cfg *config) checkExternalLinks(ctx context.Context, f *fetcher, concurrency int) {
+	targets := cfg.externalTargets()
+	queue := make(chan string)
+	wg := &sync.WaitGroup{}
+	for range max(concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range queue {
+				record := checkExternalURL(ctx, f, target)
+				cfg.mu.Lock()
+				cfg.externalPages[target] = PageData{URL: target, FetchRecord: record}
+				cfg.mu.Unlock()
+			}
+		}()
+	}
+
+	for _, target := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- target
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// checkExternalURL sends a HEAD request, falling back to GET for servers that
+// don't support HEAD. A host that can't be reached at all isn't asked again.
+func checkExternalURL(ctx context.Context, f *fetcher, rawURL string) FetchRecord {
+	result, err := f.doWithRetry(ctx, http.MethodHead, rawURL)
+	if err == nil && (result.res.StatusCode == http.StatusMethodNotAllowed || result.res.StatusCode == http.StatusNotImplemented) {
+		headAttempts := result.attempts
+		result, err = f.getWithRetry(ctx, rawURL)
+		result.attempts += headAttempts
+	}
+	if err == nil && result.res.StatusCode >= 400 {
+		err = fmt.Errorf("error (%d) getting %s", result.res.StatusCode, rawURL)
+	}
+	record := newFetchRecord(result, err)
+	// Only the status was asked for, so the body size says nothing about the page
+	record.ByteSize = 0
+	return record
+}
+
+func writeExternalLinksReport(pages map[string]PageData, links *linkGraph, filename string) error {
+	inbound := links.inboundByURL()
+	keys := []string{}
+	for key := range pages {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Compare(pages[a].URL, pages[b].URL)
+	})
+
+	rows := [][]string{}
+	for _, key := range keys {
+		page := pages[key]
+		rows = append(rows, []string{
+			page.URL,
+			formatStatusCode(page.StatusCode),
+			page.ErrorCategory,
+			page.FetchError,
+			page.FinalURL,
+			strconv.Itoa(len(links.sources(inbound[key]))),
+		})
+	}
+	return writeCSV(filename, []string{"url", "status_code", "error_category", "error", "final_url", "referring_pages"}, rows)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckExternalLinks(t *testing.T) {
+	var mu sync.Mutex
+	requested := map[string]int{}
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested[r.Method+" "+r.URL.RequestURI()]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/ok":
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/drops":
+			// Hang up without answering
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer external.Close()
+	// Both servers listen on 127.0.0.1, so reach the external one by another host name
+	externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path != "/" {
+			fmt.Fprint(w, "<html><body><h1>About</h1></body></html>")
+			return
+		}
+		fmt.Fprintf(w, `<html><body>
+			<a href="/about">About</a>
+			<a href="%[1]s/ok">Partner</a>
+			<a href="%[1]s/ok#team">Partner team</a>
+			<a href="%[1]s/ok?id=2">Partner offer</a>
+			<a href="%[1]s/no-head">Shop</a>
+			<a href="%[1]s/gone">Old friend</a>
+			<a href="%[1]s/drops">Flaky</a>
+			<a href="mailto:someone@example.com">Mail</a>
+		</body></html>`, externalURL)
+	}))
+	defer site.Close()
+
+	cfg, err := configure(site.URL, 2, 100, -1, true, testFetcherSettings())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg.enqueue(site.URL+"/", discoveredByLink, 0)
+	cfg.crawl(context.Background())
+	checkerSettings := testFetcherSettings()
+	checkerSettings.maxRetries = 0
+	checkerSettings.backoffStep = time.Millisecond
+	cfg.checkExternalLinks(context.Background(), newFetcher(checkerSettings), 2)
+
+	if len(cfg.pages) != 2 {
+		t.Errorf("Expected external pages not to be crawled, got %d pages", len(cfg.pages))
+	}
+
+	tests := []struct {
+		name               string
+		url                string
+		expectedStatus     int
+		expectedCategory   string
+		expectedRequests   []string
+		unexpectedRequests []string
+	}{
+		{
+			name:             "working link, checked once for any fragment",
+			url:              externalURL + "/ok",
+			expectedStatus:   http.StatusOK,
+			expectedRequests: []string{"HEAD /ok"},
+		},
+		{
+			name:             "another query string is another URL",
+			url:              externalURL + "/ok?id=2",
+			expectedStatus:   http.StatusOK,
+			expectedRequests: []string{"HEAD /ok?id=2"},
+		},
+		{
+			name:             "HEAD not allowed",
+			url:              externalURL + "/no-head",
+			expectedStatus:   http.StatusOK,
+			expectedRequests: []string{"HEAD /no-head", "GET /no-head"},
+		},
+		{
+			name:             "broken link",
+			url:              externalURL + "/gone",
+			expectedStatus:   http.StatusNotFound,
+			expectedCategory: errorCategoryHTTP4xx,
+			expectedRequests: []string{"HEAD /gone"},
+		},
+		{
+			name:               "a failed HEAD isn't retried as GET",
+			url:                externalURL + "/drops",
+			expectedCategory:   errorCategoryConnectionReset,
+			unexpectedRequests: []string{"GET /drops"},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			page, ok := cfg.externalPages[tc.url]
+			if !ok {
+				t.Fatalf("Test %v - %s\n%s wasn't checked", i+1, tc.name, tc.url)
+			}
+			if page.StatusCode != tc.expectedStatus || page.ErrorCategory != tc.expectedCategory {
+				t.Errorf("Test %v - %s\nExpected: %d %q\nActual: %d %q", i+1, tc.name, tc.expectedStatus, tc.expectedCategory, page.StatusCode, page.ErrorCategory)
+			}
+			for _, request := range tc.expectedRequests {
+				if requested[request] != 1 {
+					t.Errorf("Test %v - %s\nExpected %s once, got %d times", i+1, tc.name, request, requested[request])
+				}
+			}
+			for _, request := range tc.unexpectedRequests {
+				if requested[request] != 0 {
+					t.Errorf("Test %v - %s\nExpected no %s, got %d", i+1, tc.name, request, requested[request])
+				}
+			}
+		})
+	}
+
+	if len(cfg.externalPages) != len(tests) {
+		t.Errorf("Expected %d external URLs checked, got %d", len(tests), len(cfg.externalPages))
+	}
+	brokenLinks := cfg.brokenLinks()
+	if len(brokenLinks) != 2 || brokenLinks[0].TargetURL != externalURL+"/drops" || brokenLinks[1].TargetURL != externalURL+"/gone" {
+		t.Errorf("Expected broken links to %[1]s/drops and %[1]s/gone, got %+v", externalURL, brokenLinks)
+	}
+}
@@ -14,51 +14,28 @@ type PageData struct {
 	FirstParagraph string
 	OutgoingLinks  []string
 	ImageURLs      []string
-	Visits         int
+	SkipReason     string
+	DiscoveredBy   string
+	Depth          int
+	InboundLinks   int
+	OutboundLinks  int
+	ReferringPages []string
+	Truncated      bool
+	Canonical      string
+	CustomFields   map[string]string
+	FetchRecord
+	RobotsDirectives
+	PageMeta
+	StructuredData
 }
 
 func extractPageData(html, pageURL string) PageData {
-	// Get <h1>
-	h1, err := getH1FromHTML(html)
+	page, err := parsePage(html, pageURL)
 	if err != nil {
-		fmt.Printf("Error getting <h1>: %s", err.Error())
-	}
-	// Get first paragraph
-	p1, err := getFirstParagraphFromHTML(html)
-	if err != nil {
-		fmt.Printf("Error getting first paragraph: %s", err.Error())
-	}
-	// Parse url string into a url object
-	baseUrl, err := url.Parse(pageURL)
-	if err != nil {
-		fmt.Printf("Error parsing pageURL: %s", err.Error())
-		return PageData{
-			URL:            pageURL,
-			H1:             h1,
-			FirstParagraph: p1,
-			OutgoingLinks:  nil,
-			ImageURLs:      nil,
-			Visits:         1,
-		}
-	}
-	// Get outgoing links
-	outgoingLinks, err := getURLsFromHTML(html, baseUrl)
-	if err != nil {
-		fmt.Printf("Error getting outgoing links: %s", err.Error())
-	}
-	// Get img urls
-	imgURLS, err := getImagesFromHTML(html, baseUrl)
-	if err != nil {
-		fmt.Printf("Error getting images: %s", err.Error())
-	}
-	return PageData{
-		URL:            pageURL,
-		H1:             h1,
-		FirstParagraph: p1,
-		OutgoingLinks:  outgoingLinks,
-		ImageURLs:      imgURLS,
-		Visits:         1,
+		fmt.Printf("Error parsing HTML: %s", err.Error())
+		return PageData{URL: pageURL}
 	}
+	return page.extract(defaultExtractors())
 }
 
 func getH1FromHTML(html string) (string, error) {
@@ -67,7 +44,11 @@ func getH1FromHTML(html string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return strings.Trim(doc.Find("h1").First().Text(), " \n"), nil
+	return h1FromDocument(doc), nil
+}
+
+func h1FromDocument(doc *goquery.Document) string {
+	return strings.Trim(doc.Find("h1").First().Text(), " \n")
 }
 
 func getFirstParagraphFromHTML(html string) (string, error) {
@@ -76,17 +57,107 @@ func getFirstParagraphFromHTML(html string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return strings.Trim(doc.Find("p").First().Text(), " \n"), nil
+	return firstParagraphFromDocument(doc), nil
+}
+
+func firstParagraphFromDocument(doc *goquery.Document) string {
+	return strings.Trim(doc.Find("p").First().Text(), " \n")
+}
+
+// Link is a single <a href> found on a page.
+type Link struct {
+	URL string
+	// Text is the anchor text, or the alt text of its images if it has none.
+	Text  string
+	Title string
+	// Rel is the lowercased rel attribute, such as "nofollow" or "sponsored ugc".
+	Rel    string
+	Target string
+	// Section is the part of the page the link is in: nav, header, footer,
+	// main, or "" if it isn't in any of them.
+	Section string
+}
+
+// Parts of a page a link can be in.
+const (
+	linkSectionNav    = "nav"
+	linkSectionHeader = "header"
+	linkSectionFooter = "footer"
+	linkSectionMain   = "main"
+)
+
+// linkSectionRoles maps ARIA landmark roles to the section they mark.
+var linkSectionRoles = map[string]string{
+	"navigation":  linkSectionNav,
+	"banner":      linkSectionHeader,
+	"contentinfo": linkSectionFooter,
+	"main":        linkSectionMain,
 }
 
 func getURLsFromHTML(htmlBody string, baseURL *url.URL) ([]string, error) {
+	links, err := getLinksFromHTML(htmlBody, baseURL)
+	if err != nil {
+		return []string{}, err
+	}
+	return linkURLs(links), nil
+}
+
+func linkURLs(links []Link) []string {
 	result := []string{}
+	for _, link := range links {
+		result = append(result, link.URL)
+	}
+	return result
+}
 
+// documentBaseURL returns the URL relative URLs in a document are resolved
+// against: its <base href> if it declares one, else the page URL.
+func documentBaseURL(doc *goquery.Document, pageURL *url.URL) *url.URL {
+	href, exists := doc.Find("base[href]").First().Attr("href")
+	if !exists {
+		return pageURL
+	}
+	baseURL, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		fmt.Printf("couldn't parse base href %q: %v\n", href, err)
+		return pageURL
+	}
+	return pageURL.ResolveReference(baseURL)
+}
+
+// canonicalFromDocument returns the first <link rel="canonical"> of a document,
+// resolved against baseURL.
+func canonicalFromDocument(doc *goquery.Document, baseURL *url.URL) string {
+	canonical := ""
+	doc.Find("link[rel][href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		rel, _ := s.Attr("rel")
+		if !hasRel(strings.ToLower(rel), "canonical") {
+			return true
+		}
+		href, _ := s.Attr("href")
+		canonicalURL, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			fmt.Printf("couldn't parse canonical href %q: %v\n", href, err)
+			return true
+		}
+		canonical = baseURL.ResolveReference(canonicalURL).String()
+		return false
+	})
+	return canonical
+}
+
+func getLinksFromHTML(htmlBody string, baseURL *url.URL) ([]Link, error) {
 	reader := strings.NewReader(htmlBody)
 	doc, err := goquery.NewDocumentFromReader(reader)
 	if err != nil {
-		return []string{}, err
+		return []Link{}, err
 	}
+	return linksFromDocument(doc, documentBaseURL(doc, baseURL)), nil
+}
+
+// linksFromDocument returns every <a href> of a document, resolved against baseURL.
+func linksFromDocument(doc *goquery.Document, baseURL *url.URL) []Link {
+	result := []Link{}
 	// Find all links
 	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
 		urlString, exists := s.Attr("href")
@@ -99,10 +170,53 @@ func getURLsFromHTML(htmlBody string, baseURL *url.URL) ([]string, error) {
 			return
 		}
 		absoluteURL := baseURL.ResolveReference(newURL)
-		result = append(result, absoluteURL.String())
+		result = append(result, Link{
+			URL:     absoluteURL.String(),
+			Text:    anchorText(s),
+			Title:   collapseWhitespace(s.AttrOr("title", "")),
+			Rel:     strings.ToLower(strings.TrimSpace(s.AttrOr("rel", ""))),
+			Target:  strings.TrimSpace(s.AttrOr("target", "")),
+			Section: linkSection(s),
+		})
 	})
 
-	return result, nil
+	return result
+}
+
+// anchorText returns the text of a link. An image link has no text of its
+// own, so it is named by the alt text of its images instead.
+func anchorText(s *goquery.Selection) string {
+	text := collapseWhitespace(s.Text())
+	if text != "" {
+		return text
+	}
+	alts := []string{}
+	s.Find("img[alt]").Each(func(_ int, img *goquery.Selection) {
+		if alt := collapseWhitespace(img.AttrOr("alt", "")); alt != "" {
+			alts = append(alts, alt)
+		}
+	})
+	return strings.Join(alts, " ")
+}
+
+// linkSection returns the section of the page a link is in, going by its
+// closest <nav>, <header>, <footer> or <main>, or an element with the
+// matching landmark role.
+func linkSection(s *goquery.Selection) string {
+	section := ""
+	s.ParentsFiltered("nav, header, footer, main, [role]").EachWithBreak(func(_ int, parent *goquery.Selection) bool {
+		if role, ok := linkSectionRoles[strings.ToLower(strings.TrimSpace(parent.AttrOr("role", "")))]; ok {
+			section = role
+			return false
+		}
+		switch name := goquery.NodeName(parent); name {
+		case linkSectionNav, linkSectionHeader, linkSectionFooter, linkSectionMain:
+			section = name
+			return false
+		}
+		return true
+	})
+	return section
 }
 
 func getImagesFromHTML(htmlBody string, baseURL *url.URL) ([]string, error) {
@@ -111,7 +225,11 @@ func getImagesFromHTML(htmlBody string, baseURL *url.URL) ([]string, error) {
 	if err != nil {
 		return []string{}, err
 	}
+	return imagesFromDocument(doc, documentBaseURL(doc, baseURL)), nil
+}
 
+// imagesFromDocument returns the src of every <img> of a document, resolved against baseURL.
+func imagesFromDocument(doc *goquery.Document, baseURL *url.URL) []string {
 	// Find all img srcs
 	result := []string{}
 	doc.Find("img[src]").Each(func(_ int, s *goquery.Selection) {
@@ -128,5 +246,5 @@ func getImagesFromHTML(htmlBody string, baseURL *url.URL) ([]string, error) {
 		result = append(result, absoluteURL.String())
 	})
 
-	return result, nil
+	return result
 }
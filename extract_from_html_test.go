@@ -3,7 +3,10 @@ package main
 import (
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/PuerkitoBio/goquery"
 )
 
 func TestGetFirstHeaderFromHTML(t *testing.T) {
@@ -166,6 +169,23 @@ func TestGetURLsFromHTML(t *testing.T) {
 				"https://example.com/page4?q=1#section",
 			},
 		},
+		{
+			name:    "Relative URLs resolved against base href",
+			html:    `<html><head><base href="https://cdn.example.com/docs/"></head><a href="guide">Link</a><a href="/root">Link</a></html>`,
+			baseURL: baseURL,
+			expected: []string{
+				"https://cdn.example.com/docs/guide",
+				"https://cdn.example.com/root",
+			},
+		},
+		{
+			name:    "Relative base href",
+			html:    `<html><head><base href="/v2/"></head><a href="page">Link</a></html>`,
+			baseURL: baseURL,
+			expected: []string{
+				"https://example.com/v2/page",
+			},
+		},
 	}
 
 	for i, tc := range tests {
@@ -259,6 +279,14 @@ func TestGetImagesFromHTML(t *testing.T) {
 				"https://example.com/img5.gif",
 			},
 		},
+		{
+			name:    "Image resolved against base href",
+			html:    `<html><head><base href="https://static.example.com/"></head><img src="img6.png"></html>`,
+			baseURL: baseURL,
+			expected: []string{
+				"https://static.example.com/img6.png",
+			},
+		},
 	}
 
 	for i, tc := range tests {
@@ -275,6 +303,64 @@ func TestGetImagesFromHTML(t *testing.T) {
 	}
 }
 
+// getCanonicalFromHTML parses htmlBody on its own to test canonicalFromDocument.
+func getCanonicalFromHTML(htmlBody string, baseURL *url.URL) (string, error) {
+	reader := strings.NewReader(htmlBody)
+	doc, err := goquery.NewDocumentFromReader(reader)
+	if err != nil {
+		return "", err
+	}
+	return canonicalFromDocument(doc, documentBaseURL(doc, baseURL)), nil
+}
+
+func TestGetCanonicalFromHTML(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com/shoes?color=red")
+
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{
+			name:     "No canonical",
+			html:     `<html><head><title>Shoes</title></head></html>`,
+			expected: "",
+		},
+		{
+			name:     "Absolute canonical",
+			html:     `<html><head><link rel="canonical" href="https://example.com/shoes"></head></html>`,
+			expected: "https://example.com/shoes",
+		},
+		{
+			name:     "Relative canonical",
+			html:     `<html><head><link rel="canonical" href="/shoes"></head></html>`,
+			expected: "https://example.com/shoes",
+		},
+		{
+			name:     "Relative canonical against base href",
+			html:     `<html><head><base href="https://www.example.com/store/"><link rel="Canonical" href="shoes"></head></html>`,
+			expected: "https://www.example.com/store/shoes",
+		},
+		{
+			name:     "First of several canonicals",
+			html:     `<html><head><link rel="stylesheet" href="/style.css"><link rel="canonical" href="/a"><link rel="canonical" href="/b"></head></html>`,
+			expected: "https://example.com/a",
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := getCanonicalFromHTML(tc.html, baseURL)
+			if err != nil {
+				t.Errorf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+			}
+			if actual != tc.expected {
+				t.Errorf("Test %v - %s\nExpected: %v\nActual: %v", i+1, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
+
 func TestExtractPageData(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -292,6 +378,8 @@ func TestExtractPageData(t *testing.T) {
 				FirstParagraph: "",
 				OutgoingLinks:  []string{},
 				ImageURLs:      []string{},
+				PageMeta:       PageMeta{Headings: []Heading{}},
+				StructuredData: newStructuredData(),
 			},
 		},
 		{
@@ -316,6 +404,12 @@ func TestExtractPageData(t *testing.T) {
 				FirstParagraph: "First paragraph text.",
 				OutgoingLinks:  []string{"http://example.com/about", "https://external.com"},
 				ImageURLs:      []string{"http://example.com/image1.jpg", "http://example.com/image2.png"},
+				PageMeta: PageMeta{
+					Title:      "Test",
+					TitleCount: 1,
+					Headings:   []Heading{{Level: 1, Text: "Main Heading"}},
+				},
+				StructuredData: newStructuredData(),
 			},
 		},
 		{
@@ -334,6 +428,8 @@ func TestExtractPageData(t *testing.T) {
 				FirstParagraph: "No heading here.",
 				OutgoingLinks:  []string{"http://example.com/page.html"},
 				ImageURLs:      []string{},
+				PageMeta:       PageMeta{Headings: []Heading{}},
+				StructuredData: newStructuredData(),
 			},
 		},
 		{
@@ -364,6 +460,8 @@ func TestExtractPageData(t *testing.T) {
 					"http://example.com/images/pic.jpg",
 					"http://example.com/path/local.jpg",
 				},
+				PageMeta:       PageMeta{Headings: []Heading{{Level: 1, Text: "Heading"}}},
+				StructuredData: newStructuredData(),
 			},
 		},
 		{
@@ -380,6 +478,8 @@ func TestExtractPageData(t *testing.T) {
 				FirstParagraph: "",
 				OutgoingLinks:  []string{},
 				ImageURLs:      []string{},
+				PageMeta:       PageMeta{Title: "Empty", TitleCount: 1, Headings: []Heading{}},
+				StructuredData: newStructuredData(),
 			},
 		},
 		{
@@ -392,6 +492,8 @@ func TestExtractPageData(t *testing.T) {
 				FirstParagraph: "Paragraph",
 				OutgoingLinks:  []string{},
 				ImageURLs:      []string{},
+				PageMeta:       PageMeta{Headings: []Heading{{Level: 1, Text: "Test"}}},
+				StructuredData: newStructuredData(),
 			},
 		},
 	}
@@ -405,3 +507,38 @@ func TestExtractPageData(t *testing.T) {
 		})
 	}
 }
+
+func TestGetLinksFromHTML(t *testing.T) {
+	html := `<html><body>
+		<header><a href="/">Home</a>
+			<nav><a href="/shop" title=" Our  shop ">Shop</a></nav>
+		</header>
+		<div role="navigation"><a href="/about">About</a></div>
+		<main>
+			<p><a href="https://partner.com" rel="Sponsored NoFollow" target="_blank">Partner  deals</a></p>
+			<a href="/gallery"><img src="a.jpg" alt="Summer"> <img src="b.jpg" alt="collection"></a>
+			<a href="/empty"><img src="c.jpg"></a>
+		</main>
+		<div><a href="/orphan">Orphan</a></div>
+		<footer><a href="/contact" rel="ugc">Contact us</a></footer>
+	</body></html>`
+	baseURL, _ := url.Parse("https://example.com")
+
+	expected := []Link{
+		{URL: "https://example.com/", Text: "Home", Section: linkSectionHeader},
+		{URL: "https://example.com/shop", Text: "Shop", Title: "Our shop", Section: linkSectionNav},
+		{URL: "https://example.com/about", Text: "About", Section: linkSectionNav},
+		{URL: "https://partner.com", Text: "Partner deals", Rel: "sponsored nofollow", Target: "_blank", Section: linkSectionMain},
+		{URL: "https://example.com/gallery", Text: "Summer collection", Section: linkSectionMain},
+		{URL: "https://example.com/empty", Text: "", Section: linkSectionMain},
+		{URL: "https://example.com/orphan", Text: "Orphan"},
+		{URL: "https://example.com/contact", Text: "Contact us", Rel: "ugc", Section: linkSectionFooter},
+	}
+	actual, err := getLinksFromHTML(html, baseURL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected: %+v\nActual: %+v", expected, actual)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	goquery "github.com/PuerkitoBio/goquery"
+)
+
+// ParsedPage is a page's HTML, parsed once and shared by every Extractor.
+type ParsedPage struct {
+	Doc *goquery.Document
+	// URL is the page's own URL, or nil if it couldn't be parsed.
+	URL *url.URL
+	// BaseURL is what relative URLs resolve against: URL, or the document's <base href>.
+	BaseURL *url.URL
+
+	rawURL string
+	links  []Link
+}
+
+func parsePage(html, pageURL string) (*ParsedPage, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+	page := &ParsedPage{Doc: doc, rawURL: pageURL}
+	page.URL, err = url.Parse(pageURL)
+	if err != nil {
+		fmt.Printf("Error parsing pageURL: %s", err.Error())
+		page.URL = nil
+		return page, nil
+	}
+	page.BaseURL = documentBaseURL(doc, page.URL)
+	return page, nil
+}
+
+// Links returns the page's links, found on the first call and reused after that.
+// It returns nil if the page URL couldn't be parsed.
+func (p *ParsedPage) Links() []Link {
+	if p.links == nil && p.BaseURL != nil {
+		p.links = linksFromDocument(p.Doc, p.BaseURL)
+	}
+	return p.links
+}
+
+// Extractor fills in some of a page's data from its parsed document.
+type Extractor interface {
+	Extract(page *ParsedPage, data *PageData) error
+}
+
+// ExtractorFunc lets a plain function be used as an Extractor.
+type ExtractorFunc func(page *ParsedPage, data *PageData) error
+
+func (f ExtractorFunc) Extract(page *ParsedPage, data *PageData) error {
+	return f(page, data)
+}
+
+// defaultExtractors fill in every field of PageData that comes from the HTML.
+func defaultExtractors() []Extractor {
+	return []Extractor{
+		ExtractorFunc(extractH1),
+		ExtractorFunc(extractFirstParagraph),
+		ExtractorFunc(extractPageMeta),
+		ExtractorFunc(extractRobotsDirectives),
+		ExtractorFunc(extractCanonical),
+		ExtractorFunc(extractStructuredData),
+		ExtractorFunc(extractOutgoingLinks),
+		ExtractorFunc(extractImages),
+	}
+}
+
+// extract runs extractors in order over the page. An extractor failing is
+// reported and doesn't stop the others.
+func (p *ParsedPage) extract(extractors []Extractor) PageData {
+	data := PageData{URL: p.rawURL}
+	for _, extractor := range extractors {
+		if err := extractor.Extract(p, &data); err != nil {
+			fmt.Printf("Error - extract: %v\n", err)
+		}
+	}
+	return data
+}
+
+func extractH1(page *ParsedPage, data *PageData) error {
+	data.H1 = h1FromDocument(page.Doc)
+	return nil
+}
+
+func extractFirstParagraph(page *ParsedPage, data *PageData) error {
+	data.FirstParagraph = firstParagraphFromDocument(page.Doc)
+	return nil
+}
+
+func extractRobotsDirectives(page *ParsedPage, data *PageData) error {
+	data.RobotsDirectives = robotsDirectivesFromDocument(page.Doc)
+	return nil
+}
+
+func extractCanonical(page *ParsedPage, data *PageData) error {
+	if page.BaseURL != nil {
+		data.Canonical = canonicalFromDocument(page.Doc, page.BaseURL)
+	}
+	return nil
+}
+
+func extractOutgoingLinks(page *ParsedPage, data *PageData) error {
+	if page.BaseURL != nil {
+		data.OutgoingLinks = linkURLs(page.Links())
+	}
+	return nil
+}
+
+func extractImages(page *ParsedPage, data *PageData) error {
+	if page.BaseURL != nil {
+		data.ImageURLs = imagesFromDocument(page.Doc, page.BaseURL)
+	}
+	return nil
+}
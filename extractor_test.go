@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestCustomExtractor(t *testing.T) {
+	html := `<html><head><title>Shop</title></head><body>
+		<h1>Shoes</h1>
+		<a href="/a">A</a><a href="/b">B</a>
+	</body></html>`
+	page, err := parsePage(html, "https://example.com/shop")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A registered extractor sees the same document and the fields filled in before it
+	title := ""
+	linksSeen := 0
+	extractors := append(defaultExtractors(), ExtractorFunc(func(page *ParsedPage, data *PageData) error {
+		title = page.Doc.Find("title").Text()
+		linksSeen = len(data.OutgoingLinks)
+		return nil
+	}))
+	data := page.extract(extractors)
+
+	if title != "Shop" || linksSeen != 2 {
+		t.Errorf("Expected the custom extractor to see title Shop and 2 links, got %q and %d", title, linksSeen)
+	}
+	expectedLinks := []string{"https://example.com/a", "https://example.com/b"}
+	if data.H1 != "Shoes" || !reflect.DeepEqual(data.OutgoingLinks, expectedLinks) {
+		t.Errorf("Expected default fields to be filled in, got %+v", data)
+	}
+	if !reflect.DeepEqual(linkURLs(page.Links()), expectedLinks) {
+		t.Errorf("Expected links: %v\nActual: %v", expectedLinks, page.Links())
+	}
+}
+
+// largeFixturePage builds a long article page with many links and images.
+func largeFixturePage() string {
+	var b strings.Builder
+	b.WriteString(`<html><head><title>Archive</title><meta name="robots" content="noarchive">`)
+	b.WriteString(`<link rel="canonical" href="/archive"></head><body><h1>Archive</h1>`)
+	for i := range 2000 {
+		fmt.Fprintf(&b, `<section><h2>Post %[1]d</h2><p>Summary of post %[1]d with <b>some</b> markup.</p>`, i)
+		fmt.Fprintf(&b, `<a href="/posts/%[1]d" rel="bookmark">Read post %[1]d</a><img src="/images/%[1]d.jpg" alt=""></section>`, i)
+	}
+	b.WriteString(`</body></html>`)
+	return b.String()
+}
+
+func BenchmarkExtractPageData(b *testing.B) {
+	html := largeFixturePage()
+	b.SetBytes(int64(len(html)))
+	for b.Loop() {
+		extractPageData(html, "https://example.com/archive")
+	}
+}
+
+// BenchmarkExtractPageDataReparsing parses the document once per field, the
+// way extraction worked before extractors shared a parsed page.
+func BenchmarkExtractPageDataReparsing(b *testing.B) {
+	html := largeFixturePage()
+	pageURL, _ := url.Parse("https://example.com/archive")
+	b.SetBytes(int64(len(html)))
+	for b.Loop() {
+		getH1FromHTML(html)
+		getFirstParagraphFromHTML(html)
+		getRobotsDirectivesFromHTML(html)
+		getCanonicalFromHTML(html, pageURL)
+		getURLsFromHTML(html, pageURL)
+		getImagesFromHTML(html, pageURL)
+		if doc, err := goquery.NewDocumentFromReader(strings.NewReader(html)); err == nil {
+			pageMetaFromDocument(doc)
+		}
+		if doc, err := goquery.NewDocumentFromReader(strings.NewReader(html)); err == nil {
+			structuredDataFromDocument(doc)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Error categories stored on a FetchRecord.
+const (
+	errorCategoryHTTP4xx           = "http_4xx"
+	errorCategoryHTTP5xx           = "http_5xx"
+	errorCategoryNotHTML           = "not_html"
+	errorCategoryTimeout           = "timeout"
+	errorCategoryDNS               = "dns"
+	errorCategoryConnectionRefused = "connection_refused"
+	errorCategoryConnectionReset   = "connection_reset"
+	errorCategoryTLS               = "tls"
+	errorCategoryTooManyRedirects  = "too_many_redirects"
+	errorCategoryRedirectLoop      = "redirect_loop"
+	errorCategoryCanceled          = "canceled"
+	errorCategoryOther             = "other"
+)
+
+const maxRedirects = 10
+
+var (
+	errTooManyRedirects = errors.New("too many redirects")
+	errRedirectLoop     = errors.New("redirect loop")
+)
+
+// RedirectHop is one redirect response followed on the way to the final URL.
+type RedirectHop struct {
+	URL        string
+	StatusCode int
+	Location   string
+}
+
+// FetchRecord describes how fetching a page went, whether it succeeded or not.
+type FetchRecord struct {
+	StatusCode    int
+	FinalURL      string
+	Redirects     []RedirectHop
+	ContentType   string
+	ResponseTime  time.Duration
+	ByteSize      int
+	FetchAttempts int
+	FetchError    string
+	ErrorCategory string
+}
+
+type redirectsKey struct{}
+
+// recordRedirects is the client's CheckRedirect. It appends each hop to the
+// slice stored in the request context by get, and stops at a URL already
+// visited on the way.
+func recordRedirects(req *http.Request, via []*http.Request) error {
+	if hops, ok := req.Context().Value(redirectsKey{}).(*[]RedirectHop); ok && req.Response != nil {
+		*hops = append(*hops, RedirectHop{
+			URL:        via[len(via)-1].URL.String(),
+			StatusCode: req.Response.StatusCode,
+			Location:   req.Response.Header.Get("Location"),
+		})
+	}
+	for _, previous := range via {
+		if previous.URL.String() == req.URL.String() {
+			return errRedirectLoop
+		}
+	}
+	if len(via) >= maxRedirects {
+		return errTooManyRedirects
+	}
+	return nil
+}
+
+// newFetchRecord summarizes a fetch result and the error getHTML returned for it.
+func newFetchRecord(result fetchResult, err error) FetchRecord {
+	record := FetchRecord{
+		Redirects:     result.redirects,
+		ResponseTime:  result.duration,
+		ByteSize:      len(result.body),
+		FetchAttempts: result.attempts,
+	}
+	if result.res != nil {
+		record.StatusCode = result.res.StatusCode
+		record.FinalURL = result.res.Request.URL.String()
+		record.ContentType = result.res.Header.Get("Content-Type")
+	}
+	if err != nil {
+		record.FetchError = err.Error()
+		record.ErrorCategory = categorizeFetchError(record.StatusCode, err)
+	}
+	return record
+}
+
+// categorizeFetchError sorts a fetch failure into one of the error categories.
+func categorizeFetchError(statusCode int, err error) string {
+	switch {
+	case statusCode >= 500:
+		return errorCategoryHTTP5xx
+	case statusCode >= 400:
+		return errorCategoryHTTP4xx
+	case statusCode > 0:
+		return errorCategoryNotHTML
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var headerErr tls.RecordHeaderError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	switch {
+	case errors.Is(err, errRedirectLoop):
+		return errorCategoryRedirectLoop
+	case errors.Is(err, errTooManyRedirects):
+		return errorCategoryTooManyRedirects
+	case errors.As(err, &dnsErr):
+		return errorCategoryDNS
+	case errors.Is(err, errReadTimeout), errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return errorCategoryTimeout
+	case errors.Is(err, context.Canceled):
+		return errorCategoryCanceled
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return errorCategoryConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return errorCategoryConnectionReset
+	case errors.As(err, &certErr), errors.As(err, &headerErr), errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr):
+		return errorCategoryTLS
+	}
+	return errorCategoryOther
+}
+
+// formatRedirects renders a redirect chain as "301 a -> 302 b -> final".
+// A chain that never got to a final URL, such as a loop, ends at the last
+// Location instead.
+func formatRedirects(hops []RedirectHop, finalURL string) string {
+	if len(hops) == 0 {
+		return ""
+	}
+	parts := []string{}
+	for _, hop := range hops {
+		parts = append(parts, fmt.Sprintf("%d %s", hop.StatusCode, hop.URL))
+	}
+	if finalURL == "" {
+		finalURL = hops[len(hops)-1].Location
+	}
+	parts = append(parts, finalURL)
+	return strings.Join(parts, " -> ")
+}
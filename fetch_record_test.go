@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFetchRecords(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>
+				<a href="/old">old</a>
+				<a href="/missing">missing</a>
+				<a href="/file.pdf">pdf</a>
+				<a href="/broken">broken</a>
+			</body></html>`)
+		case "/old":
+			http.Redirect(w, r, "/older", http.StatusMovedPermanently)
+		case "/older":
+			http.Redirect(w, r, "/new", http.StatusFound)
+		case "/new":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html><body><h1>New</h1></body></html>")
+		case "/file.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, "%PDF-1.4")
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	settings := testFetcherSettings()
+	settings.maxRetries = 0
+	cfg, err := configure(server.URL, 2, 100, -1, true, settings)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg.enqueue(server.URL+"/", discoveredByLink, 0)
+	cfg.crawl(context.Background())
+
+	host := cfg.baseURL.Hostname()
+	tests := []struct {
+		name             string
+		path             string
+		expectedStatus   int
+		expectedCategory string
+		expectedFinalURL string
+		expectedHops     []RedirectHop
+	}{
+		{
+			name:             "redirect chain",
+			path:             "/old",
+			expectedStatus:   http.StatusOK,
+			expectedFinalURL: server.URL + "/new",
+			expectedHops: []RedirectHop{
+				{URL: server.URL + "/old", StatusCode: http.StatusMovedPermanently, Location: "/older"},
+				{URL: server.URL + "/older", StatusCode: http.StatusFound, Location: "/new"},
+			},
+		},
+		{
+			name:             "not found",
+			path:             "/missing",
+			expectedStatus:   http.StatusNotFound,
+			expectedCategory: errorCategoryHTTP4xx,
+			expectedFinalURL: server.URL + "/missing",
+			expectedHops:     []RedirectHop{},
+		},
+		{
+			name:             "not html",
+			path:             "/file.pdf",
+			expectedStatus:   http.StatusOK,
+			expectedCategory: errorCategoryNotHTML,
+			expectedFinalURL: server.URL + "/file.pdf",
+			expectedHops:     []RedirectHop{},
+		},
+		{
+			name:             "server error",
+			path:             "/broken",
+			expectedStatus:   http.StatusInternalServerError,
+			expectedCategory: errorCategoryHTTP5xx,
+			expectedFinalURL: server.URL + "/broken",
+			expectedHops:     []RedirectHop{},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			record := cfg.pages[host+tc.path].FetchRecord
+			if record.StatusCode != tc.expectedStatus || record.ErrorCategory != tc.expectedCategory || record.FinalURL != tc.expectedFinalURL {
+				t.Errorf("Test %v - %s\nExpected: %d %q %s\nActual: %d %q %s", i+1, tc.name, tc.expectedStatus, tc.expectedCategory, tc.expectedFinalURL, record.StatusCode, record.ErrorCategory, record.FinalURL)
+			}
+			if !reflect.DeepEqual(record.Redirects, tc.expectedHops) {
+				t.Errorf("Test %v - %s\nExpected hops: %+v\nActual: %+v", i+1, tc.name, tc.expectedHops, record.Redirects)
+			}
+			if record.FetchAttempts != 1 {
+				t.Errorf("Test %v - %s\nExpected 1 attempt, got %d", i+1, tc.name, record.FetchAttempts)
+			}
+		})
+	}
+}
+
+func TestCategorizeFetchError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		err        error
+		expected   string
+	}{
+		{
+			name:     "dns",
+			err:      fmt.Errorf("get: %w", &net.DNSError{Err: "no such host", Name: "nowhere.invalid"}),
+			expected: errorCategoryDNS,
+		},
+		{
+			name:     "read timeout",
+			err:      fmt.Errorf("reading: %w", errReadTimeout),
+			expected: errorCategoryTimeout,
+		},
+		{
+			name:     "too many redirects",
+			err:      fmt.Errorf("get: %w", errTooManyRedirects),
+			expected: errorCategoryTooManyRedirects,
+		},
+		{
+			name:     "redirect loop",
+			err:      fmt.Errorf("get: %w", errRedirectLoop),
+			expected: errorCategoryRedirectLoop,
+		},
+		{
+			name:       "404",
+			statusCode: 404,
+			err:        errors.New("error (404)"),
+			expected:   errorCategoryHTTP4xx,
+		},
+		{
+			name:     "anything else",
+			err:      errors.New("boom"),
+			expected: errorCategoryOther,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := categorizeFetchError(tc.statusCode, tc.err)
+			if actual != tc.expected {
+				t.Errorf("Test %v - %s\nExpected: %s\nActual: %s", i+1, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
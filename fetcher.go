@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+type fetcherSettings struct {
+	connectTimeout      time.Duration
+	readTimeout         time.Duration
+	totalTimeout        time.Duration
+	maxBodyBytes        int64
+	maxIdleConnsPerHost int
+	maxRetries          int
+	retryBaseDelay      time.Duration
+	retryMaxDelay       time.Duration
+	requestsPerSecond   float64
+	minDelay            time.Duration
+	backoffStep         time.Duration
+}
+
+func defaultFetcherSettings() fetcherSettings {
+	return fetcherSettings{
+		connectTimeout:      10 * time.Second,
+		readTimeout:         30 * time.Second,
+		totalTimeout:        time.Minute,
+		maxBodyBytes:        10 * 1024 * 1024,
+		maxIdleConnsPerHost: 16,
+		maxRetries:          3,
+		retryBaseDelay:      time.Second,
+		retryMaxDelay:       time.Minute,
+		requestsPerSecond:   5,
+		backoffStep:         time.Second,
+	}
+}
+
+// fetcher does every HTTP request of a crawl through one pooled client,
+// keeping to each host's rate limit.
+type fetcher struct {
+	client   *http.Client
+	limiter  *hostLimiter
+	settings fetcherSettings
+}
+
+type fetchResult struct {
+	res       *http.Response
+	body      []byte
+	truncated bool
+	attempts  int
+	redirects []RedirectHop
+	duration  time.Duration
+}
+
+var errReadTimeout = errors.New("read timeout")
+
+func newFetcher(settings fetcherSettings) *fetcher {
+	dialer := &net.Dialer{
+		Timeout:   settings.connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   settings.connectTimeout,
+		ResponseHeaderTimeout: settings.readTimeout,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   settings.maxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+	return &fetcher{
+		client: &http.Client{
+			Transport:     transport,
+			Timeout:       settings.totalTimeout,
+			CheckRedirect: recordRedirects,
+		},
+		limiter:  newHostLimiter(settings.requestsPerSecond, settings.minDelay, settings.backoffStep),
+		settings: settings,
+	}
+}
+
+// withMaxBodyBytes returns a fetcher that shares f's client and rate limits but
+// reads bodies of up to maxBytes.
+func (f *fetcher) withMaxBodyBytes(maxBytes int64) *fetcher {
+	limited := *f
+	limited.settings.maxBodyBytes = maxBytes
+	return &limited
+}
+
+// get requests rawURL and reads at most maxBodyBytes of the body (0 for no limit).
+// Anything past that is dropped and the result is flagged as truncated. The read timeout
+// applies to every read, so a server that stalls mid-body is given up on.
+// Redirects followed and the time taken are kept on the result, even on error.
+func (f *fetcher) get(ctx context.Context, rawURL string) (fetchResult, error) {
+	return f.do(ctx, "GET", rawURL)
+}
+
+// do is get with any request method.
+func (f *fetcher) do(ctx context.Context, method, rawURL string) (fetchResult, error) {
+	redirects := []RedirectHop{}
+	ctx = context.WithValue(ctx, redirectsKey{}, &redirects)
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return fetchResult{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	if err := f.limiter.wait(ctx, req.URL.Host); err != nil {
+		return fetchResult{}, err
+	}
+	start := time.Now()
+	res, err := f.client.Do(req)
+	if err != nil {
+		// Our own interrupt isn't the host's fault
+		if isTransientError(err) && ctx.Err() == nil {
+			f.limiter.observeError(req.URL.Host)
+		}
+		return fetchResult{redirects: redirects, duration: time.Since(start)}, err
+	}
+	defer res.Body.Close()
+	f.limiter.observe(req.URL.Host, res.StatusCode, time.Since(start))
+
+	var body io.Reader = res.Body
+	if f.settings.readTimeout > 0 {
+		stalled := time.AfterFunc(f.settings.readTimeout, func() { cancel(errReadTimeout) })
+		defer stalled.Stop()
+		body = &idleTimeoutReader{r: res.Body, timer: stalled, timeout: f.settings.readTimeout}
+	}
+
+	if f.settings.maxBodyBytes > 0 {
+		body = io.LimitReader(body, f.settings.maxBodyBytes+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		if context.Cause(ctx) == errReadTimeout {
+			err = fmt.Errorf("reading %s: %w", rawURL, errReadTimeout)
+			f.limiter.observeError(req.URL.Host)
+		}
+		return fetchResult{redirects: redirects, duration: time.Since(start)}, err
+	}
+	result := fetchResult{res: res, body: data, redirects: redirects, duration: time.Since(start)}
+	if f.settings.maxBodyBytes > 0 && int64(len(data)) > f.settings.maxBodyBytes {
+		result.body = data[:f.settings.maxBodyBytes]
+		result.truncated = true
+	}
+	return result, nil
+}
+
+// idleTimeoutReader restarts timer before every read.
+type idleTimeoutReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	r.timer.Reset(r.timeout)
+	return r.r.Read(p)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFetcherSettings are the default settings without the politeness limits,
+// which would only slow tests against a local server down.
+func testFetcherSettings() fetcherSettings {
+	settings := defaultFetcherSettings()
+	settings.requestsPerSecond = 0
+	settings.minDelay = 0
+	return settings
+}
+
+func TestFetcherGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/stall":
+			w.Write([]byte("<html>"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case "/slow-headers":
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte("<html></html>"))
+		default:
+			w.Write([]byte(strings.Repeat("a", 100)))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name              string
+		path              string
+		settings          func(*fetcherSettings)
+		expectedLength    int
+		expectedTruncated bool
+		wantErr           bool
+	}{
+		{
+			name:           "body under the limit",
+			path:           "/",
+			settings:       func(s *fetcherSettings) { s.maxBodyBytes = 100 },
+			expectedLength: 100,
+		},
+		{
+			name:              "body over the limit is truncated",
+			path:              "/",
+			settings:          func(s *fetcherSettings) { s.maxBodyBytes = 10 },
+			expectedLength:    10,
+			expectedTruncated: true,
+		},
+		{
+			name:           "no limit",
+			path:           "/",
+			settings:       func(s *fetcherSettings) { s.maxBodyBytes = 0 },
+			expectedLength: 100,
+		},
+		{
+			name:     "stalled body hits the read timeout",
+			path:     "/stall",
+			settings: func(s *fetcherSettings) { s.readTimeout = 50 * time.Millisecond },
+			wantErr:  true,
+		},
+		{
+			name:     "slow headers hit the read timeout",
+			path:     "/slow-headers",
+			settings: func(s *fetcherSettings) { s.readTimeout = 50 * time.Millisecond },
+			wantErr:  true,
+		},
+		{
+			name:     "slow headers hit the total timeout",
+			path:     "/slow-headers",
+			settings: func(s *fetcherSettings) { s.totalTimeout = 50 * time.Millisecond },
+			wantErr:  true,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			settings := testFetcherSettings()
+			tc.settings(&settings)
+			f := newFetcher(settings)
+			result, err := f.get(context.Background(), server.URL+tc.path)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Test %v - %s\nExpected an error", i+1, tc.name)
+				}
+				// A timeout is the host struggling, so it gets more time between requests
+				host := strings.TrimPrefix(server.URL, "http://")
+				if interval := f.limiter.hosts[host].interval; interval <= 0 {
+					t.Errorf("Test %v - %s\nExpected the host's request interval to widen, got %v", i+1, tc.name, interval)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+			}
+			if len(result.body) != tc.expectedLength || result.truncated != tc.expectedTruncated {
+				t.Errorf("Test %v - %s\nExpected length %d, truncated %v\nActual: %d, %v", i+1, tc.name, tc.expectedLength, tc.expectedTruncated, len(result.body), result.truncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const interruptedReason = "not crawled: crawl interrupted"
+
+// unknownDepth marks pages whose click depth from the base URL isn't known,
+// such as pages only listed in a sitemap.
+const unknownDepth = -1
+
+type frontierItem struct {
+	rawURL        string
+	normalizedURL string
+	source        string
+	depth         int
+}
+
+// prepareItem parses and normalizes a URL, returning false if it's invalid, on
+// another site or out of scope. URLs out of scope are kept in cfg.excluded.
+func (cfg *config) prepareItem(rawURL, source string, depth int) (frontierItem, bool) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		fmt.Printf("Error - enqueue: couldn't parse URL '%s': %v\n", rawURL, err)
+		return frontierItem{}, false
+	}
+
+	// stay within the same site
+	if !cfg.isSameSite(parsedURL) {
+		return frontierItem{}, false
+	}
+
+	normalizedURL, err := cfg.normalize(rawURL)
+	if err != nil {
+		fmt.Printf("Error - normalizedURL: %v\n", err)
+		return frontierItem{}, false
+	}
+
+	if reason := cfg.filter.excludedBy(parsedURL); reason != "" {
+		cfg.mu.Lock()
+		defer cfg.mu.Unlock()
+		cfg.excluded[normalizedURL] = PageData{
+			URL:          rawURL,
+			SkipReason:   reason,
+			DiscoveredBy: mergeDiscovery(cfg.excluded[normalizedURL].DiscoveredBy, source),
+			Depth:        unknownDepth,
+		}
+		return frontierItem{}, false
+	}
+
+	return frontierItem{
+		rawURL:        rawURL,
+		normalizedURL: normalizedURL,
+		source:        source,
+		depth:         depth,
+	}, true
+}
+
+// enqueue adds a URL to the back of the frontier unless it has been seen before,
+// it's on another site, or the page limit has been reached.
+func (cfg *config) enqueue(rawURL, source string, depth int) {
+	item, ok := cfg.prepareItem(rawURL, source, depth)
+	if !ok {
+		return
+	}
+
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.addToFrontier(item)
+}
+
+// enqueueSitemapSeed holds a sitemap URL back until every page reachable through
+// links has been crawled, so link depths are measured before sitemap-only pages
+// take up room under maxPages.
+func (cfg *config) enqueueSitemapSeed(rawURL string) {
+	item, ok := cfg.prepareItem(rawURL, discoveredBySitemap, unknownDepth)
+	if !ok {
+		return
+	}
+
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.sitemapSeeds = append(cfg.sitemapSeeds, item)
+}
+
+// addToFrontier must be called with cfg.mu held. Pages are reserved in cfg.pages
+// as soon as they are queued, so maxPages is never exceeded. A page seen again
+// keeps the shortest depth it was found at. New pages that look like part of a
+// crawler trap are set aside in cfg.traps instead.
+func (cfg *config) addToFrontier(item frontierItem) {
+	if cfg.stopped {
+		return
+	}
+	if page, visited := cfg.pages[item.normalizedURL]; visited {
+		page.DiscoveredBy = mergeDiscovery(page.DiscoveredBy, item.source)
+		if item.depth != unknownDepth && (page.Depth == unknownDepth || item.depth < page.Depth) {
+			page.Depth = item.depth
+		}
+		cfg.pages[item.normalizedURL] = page
+		return
+	}
+	if len(cfg.pages) >= cfg.maxPages {
+		return
+	}
+	if trap := cfg.traps.check(item.normalizedURL); trap.Reason != "" {
+		cfg.traps.found[item.normalizedURL] = trap
+		return
+	}
+
+	cfg.traps.observe(item.normalizedURL)
+	cfg.pages[item.normalizedURL] = PageData{URL: item.rawURL, DiscoveredBy: item.source, Depth: item.depth}
+	cfg.frontier = append(cfg.frontier, item)
+	cfg.frontierCond.Signal()
+}
+
+// depthReady reports whether a page at depth can be crawled yet. Every worker
+// still crawling a page at least two levels shallower could find a shorter path
+// to it, so it has to wait for them. This keeps recorded depths exact.
+func (cfg *config) depthReady(depth int) bool {
+	if depth == unknownDepth {
+		return true
+	}
+	for inFlightDepth, count := range cfg.inFlightDepths {
+		if count > 0 && inFlightDepth != unknownDepth && inFlightDepth < depth-1 {
+			return false
+		}
+	}
+	return true
+}
+
+// next blocks until a URL can be crawled and returns it. Held back sitemap seeds
+// are released once the link frontier runs dry. It returns false once the crawl
+// is stopped, or there is nothing left to queue and no worker is still crawling.
+func (cfg *config) next() (frontierItem, bool) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	for {
+		if cfg.stopped {
+			return frontierItem{}, false
+		}
+		if len(cfg.frontier) > 0 {
+			// The depth may have shrunk since the URL was queued
+			depth := cfg.pages[cfg.frontier[0].normalizedURL].Depth
+			if cfg.depthReady(depth) {
+				item := cfg.frontier[0]
+				item.depth = depth
+				cfg.frontier = cfg.frontier[1:]
+				cfg.inFlight++
+				cfg.inFlightDepths[depth]++
+				cfg.active[item.normalizedURL] = item
+				return item, true
+			}
+		} else if cfg.inFlight == 0 {
+			if len(cfg.sitemapSeeds) == 0 {
+				cfg.frontierCond.Broadcast()
+				return frontierItem{}, false
+			}
+			for _, item := range cfg.sitemapSeeds {
+				cfg.addToFrontier(item)
+			}
+			cfg.sitemapSeeds = nil
+			continue
+		}
+		cfg.frontierCond.Wait()
+	}
+}
+
+// done marks a URL returned by next as fully crawled.
+func (cfg *config) done(item frontierItem) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	cfg.inFlight--
+	cfg.inFlightDepths[item.depth]--
+	delete(cfg.active, item.normalizedURL)
+	cfg.frontierCond.Broadcast()
+}
+
+// requeue puts back a URL whose fetch was cut short by an interruption, so it
+// is kept in checkpoints and crawled again on resume.
+func (cfg *config) requeue(item frontierItem) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	delete(cfg.active, item.normalizedURL)
+	cfg.frontier = append([]frontierItem{item}, cfg.frontier...)
+}
+
+// stop keeps workers from taking or queueing any more URLs.
+func (cfg *config) stop() {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.stopped = true
+	cfg.frontierCond.Broadcast()
+}
+
+// markInterrupted flags every page still waiting in the frontier as not crawled.
+func (cfg *config) markInterrupted() {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	for _, item := range cfg.frontier {
+		page := cfg.pages[item.normalizedURL]
+		page.SkipReason = interruptedReason
+		cfg.pages[item.normalizedURL] = page
+	}
+}
+
+// crawl starts maxConcurrency workers and waits until the frontier is exhausted
+// or ctx is done. Once ctx is done no new URLs are crawled, and fetches already
+// in flight get cfg.gracePeriod to finish before they are cancelled too.
+// It returns false if the crawl was cut short.
+func (cfg *config) crawl(ctx context.Context) (complete bool) {
+	fetchCtx, cancelFetches := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelFetches()
+	stopOnDone := context.AfterFunc(ctx, func() {
+		cfg.stop()
+		time.AfterFunc(cfg.gracePeriod, cancelFetches)
+	})
+	defer stopOnDone()
+
+	if cfg.checkpointFile != "" {
+		stopCheckpoints := cfg.startCheckpoints()
+		defer stopCheckpoints()
+	}
+
+	for range cfg.maxConcurrency {
+		cfg.wg.Add(1)
+		go func() {
+			defer cfg.wg.Done()
+			for {
+				item, ok := cfg.next()
+				if !ok {
+					return
+				}
+				cfg.crawlPage(fetchCtx, item)
+				cfg.done(item)
+			}
+		}()
+	}
+	cfg.wg.Wait()
+
+	complete = ctx.Err() == nil
+	if cfg.checkpointFile != "" {
+		if err := cfg.saveCheckpoint(); err != nil {
+			fmt.Printf("Error - saveCheckpoint: %v\n", err)
+		}
+	}
+	if !complete {
+		cfg.markInterrupted()
+	}
+	return complete
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestSite serves a small tree of linked pages,
+// recording the order in which paths are requested.
+func newTestSite(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	links := map[string][]string{
+		"/":  {"/a", "/b"},
+		"/a": {"/a1", "/a2", "/"},
+		"/b": {"/b1", "/b2"},
+		"/c": {"/c1"},
+	}
+
+	return newRecordingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body><h1>%s</h1>", r.URL.Path)
+		for _, link := range links[r.URL.Path] {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, link)
+		}
+		fmt.Fprint(w, "</body></html>")
+	})
+}
+
+// newRecordingServer serves handler and returns a function listing the paths
+// requested so far, in order.
+func newRecordingServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	requested := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(requested)
+	}
+}
+
+func TestCrawlBreadthFirst(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxConcurrency int
+		maxPages       int
+		expectedPages  int
+		expectedOrder  []string
+	}{
+		{
+			name:           "whole site in breadth-first order",
+			maxConcurrency: 1,
+			maxPages:       100,
+			expectedPages:  7,
+			expectedOrder:  []string{"/", "/a", "/b", "/a1", "/a2", "/b1", "/b2"},
+		},
+		{
+			name:           "max pages enforced exactly",
+			maxConcurrency: 1,
+			maxPages:       4,
+			expectedPages:  4,
+			expectedOrder:  []string{"/", "/a", "/b", "/a1"},
+		},
+		{
+			name:           "max pages enforced with many workers",
+			maxConcurrency: 8,
+			maxPages:       5,
+			expectedPages:  5,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, requested := newTestSite(t)
+			cfg, err := configure(server.URL, tc.maxConcurrency, tc.maxPages, -1, true, testFetcherSettings())
+			if err != nil {
+				t.Fatalf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+			}
+
+			cfg.enqueue(server.URL+"/", discoveredByLink, 0)
+			cfg.crawl(context.Background())
+
+			if len(cfg.pages) != tc.expectedPages {
+				t.Errorf("Test %v - %s\nExpected %d pages, got %d", i+1, tc.name, tc.expectedPages, len(cfg.pages))
+			}
+			if tc.expectedOrder != nil && !reflect.DeepEqual(requested(), tc.expectedOrder) {
+				t.Errorf("Test %v - %s\nExpected order: %v\nActual: %v", i+1, tc.name, tc.expectedOrder, requested())
+			}
+		})
+	}
+}
+
+func TestCrawlDepth(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxConcurrency int
+		maxDepth       int
+		sitemapURLs    []string
+		expectedDepths map[string]int
+		// expectedDiscovery, if set, is how some of the pages must have been discovered
+		expectedDiscovery map[string]string
+	}{
+		{
+			name:           "shortest depth with many workers",
+			maxConcurrency: 4,
+			maxDepth:       -1,
+			expectedDepths: map[string]int{"": 0, "/a": 1, "/b": 1, "/a1": 2, "/a2": 2, "/b1": 2, "/b2": 2},
+		},
+		{
+			name:           "max depth stops following links",
+			maxConcurrency: 2,
+			maxDepth:       1,
+			expectedDepths: map[string]int{"": 0, "/a": 1, "/b": 1},
+		},
+		{
+			name:              "sitemap-only pages have unknown depth",
+			maxConcurrency:    1,
+			maxDepth:          -1,
+			sitemapURLs:       []string{"/a", "/c"},
+			expectedDepths:    map[string]int{"": 0, "/a": 1, "/b": 1, "/a1": 2, "/a2": 2, "/b1": 2, "/b2": 2, "/c": unknownDepth, "/c1": unknownDepth},
+			expectedDiscovery: map[string]string{"": discoveredByLink, "/a": discoveredByBoth, "/b": discoveredByLink, "/c": discoveredBySitemap, "/c1": discoveredByLink},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, _ := newTestSite(t)
+			cfg, err := configure(server.URL, tc.maxConcurrency, 100, tc.maxDepth, true, testFetcherSettings())
+			if err != nil {
+				t.Fatalf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+			}
+
+			cfg.enqueue(server.URL+"/", discoveredByLink, 0)
+			for _, path := range tc.sitemapURLs {
+				cfg.enqueueSitemapSeed(server.URL + path)
+			}
+			cfg.crawl(context.Background())
+
+			host := cfg.baseURL.Hostname()
+			actual := make(map[string]int)
+			for normalizedURL, page := range cfg.pages {
+				actual[strings.TrimPrefix(normalizedURL, host)] = page.Depth
+			}
+			if !reflect.DeepEqual(actual, tc.expectedDepths) {
+				t.Errorf("Test %v - %s\nExpected: %v\nActual: %v", i+1, tc.name, tc.expectedDepths, actual)
+			}
+			for path, expected := range tc.expectedDiscovery {
+				if discoveredBy := cfg.pages[host+path].DiscoveredBy; discoveredBy != expected {
+					t.Errorf("Test %v - %s\nExpected %q to be discovered by %s, got %q", i+1, tc.name, path, expected, discoveredBy)
+				}
+			}
+		})
+	}
+}
+
+func TestCrawlInterrupted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path != "/" {
+			// Hang until the crawler gives up on the request
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, `<html><body><a href="/a">a</a><a href="/b">b</a><a href="/c">c</a></body></html>`)
+	}))
+	defer server.Close()
+
+	cfg, err := configure(server.URL, 1, 100, -1, true, testFetcherSettings())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg.gracePeriod = 50 * time.Millisecond
+	cfg.enqueue(server.URL+"/", discoveredByLink, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	complete := cfg.crawl(ctx)
+
+	if complete {
+		t.Errorf("Expected the crawl to be reported as incomplete")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the crawl to stop soon after cancellation, took %v", elapsed)
+	}
+	host := cfg.baseURL.Hostname()
+	for _, path := range []string{"/a", "/b", "/c"} {
+		if reason := cfg.pages[host+path].SkipReason; reason != interruptedReason {
+			t.Errorf("Expected %s to be marked as interrupted, got %q", path, reason)
+		}
+		if pageURL := cfg.pages[host+path].URL; pageURL != server.URL+path {
+			t.Errorf("Expected %s to keep its full URL, got %q", path, pageURL)
+		}
+	}
+	if reason := cfg.pages[host].SkipReason; reason != "" {
+		t.Errorf("Expected the home page to be crawled, got skip reason %q", reason)
+	}
+}
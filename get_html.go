@@ -1,34 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 )
 
-func getHTML(rawURL string) (string, error) {
-	req, err := http.NewRequest("GET", rawURL, nil)
-	if err != nil {
-		return "", nil
-	}
-	req.Header.Set("User-Agent", "BootCrawler/1.0")
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	body, err := io.ReadAll(res.Body)
+const userAgent = "BootCrawler/1.0"
+
+// getHTML fetches an HTML page, retrying transient failures. The result is
+// returned even on error, so callers can see how many attempts were made.
+func (f *fetcher) getHTML(ctx context.Context, rawURL string) (fetchResult, error) {
+	result, err := f.getWithRetry(ctx, rawURL)
 	if err != nil {
-		return "", err
+		return result, err
 	}
-	defer res.Body.Close()
 	// Check status code
-	if res.StatusCode >= 400 {
-		return "", fmt.Errorf("error (%d) getting %s", res.StatusCode, rawURL)
+	if result.res.StatusCode >= 400 {
+		return result, fmt.Errorf("error (%d) getting %s", result.res.StatusCode, rawURL)
 	}
 	// Check Content-Type (text/html)
-	if !strings.Contains(res.Header.Get("Content-Type"), "text/html") {
-		return "", fmt.Errorf("content-type is not text/html fo %s", rawURL)
+	if !strings.Contains(result.res.Header.Get("Content-Type"), "text/html") {
+		return result, fmt.Errorf("content-type is not text/html for %s", rawURL)
 	}
-	return string(body), nil
+	return result, nil
 }
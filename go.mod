@@ -4,6 +4,6 @@ go 1.24.4
 
 require (
 	github.com/PuerkitoBio/goquery v1.10.3
-	github.com/andybalholm/cascadia v1.3.3 // indirect
-	golang.org/x/net v0.39.0 // indirect
+	github.com/andybalholm/cascadia v1.3.3
+	golang.org/x/net v0.39.0
 )
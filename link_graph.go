@@ -0,0 +1,109 @@
+package main
+
+import (
+	"slices"
+)
+
+// linkEdge is a directed link from one crawled page to another URL.
+// Source and Target are normalized URLs.
+type linkEdge struct {
+	Source     string
+	Target     string
+	TargetURL  string
+	AnchorText string
+	Title      string
+	Rel        string
+	// LinkTarget is the link's target attribute, such as "_blank".
+	LinkTarget string
+	Section    string
+}
+
+type linkGraph struct {
+	edges    []linkEdge
+	inbound  map[string][]int
+	outbound map[string][]int
+}
+
+func newLinkGraph() *linkGraph {
+	return &linkGraph{
+		inbound:  make(map[string][]int),
+		outbound: make(map[string][]int),
+	}
+}
+
+func (g *linkGraph) addEdge(edge linkEdge) {
+	g.edges = append(g.edges, edge)
+	i := len(g.edges) - 1
+	g.inbound[edge.Target] = append(g.inbound[edge.Target], i)
+	g.outbound[edge.Source] = append(g.outbound[edge.Source], i)
+}
+
+// referringPages returns the distinct pages linking to target, sorted.
+func (g *linkGraph) referringPages(target string) []string {
+	return g.sources(g.inbound[target])
+}
+
+// sources returns the distinct source pages of the given edges, sorted.
+func (g *linkGraph) sources(edges []int) []string {
+	sources := []string{}
+	for _, i := range edges {
+		sources = append(sources, g.edges[i].Source)
+	}
+	slices.Sort(sources)
+	return slices.Compact(sources)
+}
+
+// inboundByURL indexes the edges by externalKey of their link URL, for
+// targets on other sites, which aren't keyed by normalized URL.
+func (g *linkGraph) inboundByURL() map[string][]int {
+	inbound := make(map[string][]int)
+	for i, edge := range g.edges {
+		key := externalKey(edge.TargetURL)
+		inbound[key] = append(inbound[key], i)
+	}
+	return inbound
+}
+
+// recordLinks stores the links found on a crawled page in the link graph.
+func (cfg *config) recordLinks(sourceNormalizedURL string, links []Link) {
+	edges := make([]linkEdge, 0, len(links))
+	for _, link := range links {
+		target, err := cfg.normalize(link.URL)
+		if err != nil {
+			continue
+		}
+		edges = append(edges, linkEdge{
+			Source:     sourceNormalizedURL,
+			Target:     target,
+			TargetURL:  link.URL,
+			AnchorText: link.Text,
+			Title:      link.Title,
+			Rel:        link.Rel,
+			LinkTarget: link.Target,
+			Section:    link.Section,
+		})
+	}
+
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	for _, edge := range edges {
+		cfg.links.addEdge(edge)
+	}
+}
+
+// applyLinkGraph fills each page's inbound and outbound counts and the pages
+// referring to it. Call it once crawling is finished.
+func (cfg *config) applyLinkGraph() {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	for normalizedURL, page := range cfg.pages {
+		page.InboundLinks = len(cfg.links.inbound[normalizedURL])
+		page.OutboundLinks = len(cfg.links.outbound[normalizedURL])
+		page.ReferringPages = []string{}
+		for _, source := range cfg.links.referringPages(normalizedURL) {
+			page.ReferringPages = append(page.ReferringPages, cfg.pageURL(source))
+		}
+		cfg.pages[normalizedURL] = page
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestApplyLinkGraph(t *testing.T) {
+	server, _ := newTestSite(t)
+	cfg, err := configure(server.URL, 3, 100, -1, true, testFetcherSettings())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg.enqueue(server.URL+"/", discoveredByLink, 0)
+	cfg.crawl(context.Background())
+	cfg.applyLinkGraph()
+
+	host := cfg.baseURL.Hostname()
+	tests := []struct {
+		name             string
+		normalizedURL    string
+		expectedInbound  int
+		expectedOutbound int
+		expectedSources  []string
+	}{
+		{
+			name:             "home page linked back from a child",
+			normalizedURL:    host,
+			expectedInbound:  1,
+			expectedOutbound: 2,
+			expectedSources:  []string{server.URL + "/a"},
+		},
+		{
+			name:             "leaf page",
+			normalizedURL:    host + "/b1",
+			expectedInbound:  1,
+			expectedOutbound: 0,
+			expectedSources:  []string{server.URL + "/b"},
+		},
+		{
+			name:             "page with three links",
+			normalizedURL:    host + "/a",
+			expectedInbound:  1,
+			expectedOutbound: 3,
+			expectedSources:  []string{server.URL + "/"},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			page := cfg.pages[tc.normalizedURL]
+			if page.InboundLinks != tc.expectedInbound || page.OutboundLinks != tc.expectedOutbound {
+				t.Errorf("Test %v - %s\nExpected in/out: %d/%d\nActual: %d/%d", i+1, tc.name, tc.expectedInbound, tc.expectedOutbound, page.InboundLinks, page.OutboundLinks)
+			}
+			if !reflect.DeepEqual(page.ReferringPages, tc.expectedSources) {
+				t.Errorf("Test %v - %s\nExpected sources: %v\nActual: %v", i+1, tc.name, tc.expectedSources, page.ReferringPages)
+			}
+		})
+	}
+
+	edge := cfg.links.edges[cfg.links.inbound[host+"/b"][0]]
+	if edge.AnchorText != "link" || edge.Source != host {
+		t.Errorf("Unexpected edge: %+v", edge)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const filenameLinksCSV = "links.csv"
+
+// Problems with a link's anchor text.
+const (
+	anchorIssueEmpty   = "empty"
+	anchorIssueGeneric = "generic"
+)
+
+// genericAnchorTexts say nothing about the page they link to.
+var genericAnchorTexts = map[string]bool{
+	"click here": true,
+	"click":      true,
+	"here":       true,
+	"read more":  true,
+	"more":       true,
+	"learn more": true,
+	"more info":  true,
+	"details":    true,
+	"link":       true,
+	"this":       true,
+	"this page":  true,
+	"continue":   true,
+	"go":         true,
+}
+
+// exportedLink is a link found on a crawled page, with its context.
+type exportedLink struct {
+	SourceURL   string
+	LinkURL     string
+	AnchorText  string
+	Title       string
+	Rel         string
+	Target      string
+	Section     string
+	Internal    bool
+	AnchorIssue string
+}
+
+// anchorIssue returns what is wrong with a link's anchor text, or "".
+func anchorIssue(text string) string {
+	text = strings.ToLower(strings.Trim(text, " .,:;!?»›→>-"))
+	switch {
+	case text == "":
+		return anchorIssueEmpty
+	case genericAnchorTexts[text]:
+		return anchorIssueGeneric
+	}
+	return ""
+}
+
+// allLinks lists every link found on the crawled pages, sorted by source page.
+// Links on one page keep their order in the document.
+func (cfg *config) allLinks() []exportedLink {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	result := []exportedLink{}
+	for _, edge := range cfg.links.edges {
+		targetURL, err := url.Parse(edge.TargetURL)
+		result = append(result, exportedLink{
+			SourceURL:   cfg.pageURL(edge.Source),
+			LinkURL:     edge.TargetURL,
+			AnchorText:  edge.AnchorText,
+			Title:       edge.Title,
+			Rel:         edge.Rel,
+			Target:      edge.LinkTarget,
+			Section:     edge.Section,
+			Internal:    err == nil && cfg.isSameSite(targetURL),
+			AnchorIssue: anchorIssue(edge.AnchorText),
+		})
+	}
+
+	slices.SortStableFunc(result, func(a, b exportedLink) int {
+		return cmp.Compare(a.SourceURL, b.SourceURL)
+	})
+	return result
+}
+
+func writeLinksReport(links []exportedLink, filename string) error {
+	rows := [][]string{}
+	for _, link := range links {
+		rows = append(rows, []string{
+			link.SourceURL,
+			link.LinkURL,
+			link.AnchorText,
+			link.Title,
+			link.Rel,
+			link.Target,
+			link.Section,
+			strconv.FormatBool(link.Internal),
+			link.AnchorIssue,
+		})
+	}
+	return writeCSV(filename, []string{"source_page_url", "link_url", "anchor_text", "title", "rel", "target", "section", "internal", "anchor_issue"}, rows)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnchorIssue(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{text: "", expected: anchorIssueEmpty},
+		{text: "»", expected: anchorIssueEmpty},
+		{text: "Click here", expected: anchorIssueGeneric},
+		{text: "Read more »", expected: anchorIssueGeneric},
+		{text: "here.", expected: anchorIssueGeneric},
+		{text: "Read more about red shoes", expected: ""},
+		{text: "Contact us", expected: ""},
+	}
+
+	for i, tc := range tests {
+		if actual := anchorIssue(tc.text); actual != tc.expected {
+			t.Errorf("Test %v - %q\nExpected: %q\nActual: %q", i+1, tc.text, tc.expected, actual)
+		}
+	}
+}
+
+func TestAllLinks(t *testing.T) {
+	cfg, err := configure("https://example.com", 1, 10, -1, true, defaultFetcherSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.pages["example.com/b"] = PageData{URL: "https://example.com/b"}
+	cfg.pages["example.com/a"] = PageData{URL: "https://example.com/a"}
+	cfg.recordLinks("example.com/b", []Link{
+		{URL: "https://example.com/a", Text: "Click here", Section: linkSectionMain},
+	})
+	cfg.recordLinks("example.com/a", []Link{
+		{URL: "https://other.com/", Text: "Partner", Rel: "sponsored", Target: "_blank", Section: linkSectionFooter},
+		{URL: "https://example.com/b", Text: "", Title: "Go to B", Section: linkSectionNav},
+		{URL: "https://example.com/b", Text: "Page B"},
+	})
+
+	expected := []exportedLink{
+		{SourceURL: "https://example.com/a", LinkURL: "https://other.com/", AnchorText: "Partner", Rel: "sponsored", Target: "_blank", Section: linkSectionFooter},
+		{SourceURL: "https://example.com/a", LinkURL: "https://example.com/b", Title: "Go to B", Section: linkSectionNav, Internal: true, AnchorIssue: anchorIssueEmpty},
+		{SourceURL: "https://example.com/a", LinkURL: "https://example.com/b", AnchorText: "Page B", Internal: true},
+		{SourceURL: "https://example.com/b", LinkURL: "https://example.com/a", AnchorText: "Click here", Section: linkSectionMain, Internal: true, AnchorIssue: anchorIssueGeneric},
+	}
+	actual := cfg.allLinks()
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected: %+v\nActual: %+v", expected, actual)
+	}
+}
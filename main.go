@@ -1,61 +1,317 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"maps"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
 const filenameCSV = "report.csv"
 
+const usage = "Usage: [flags] <url> <max concurrency> <max pages to crawl>"
+
+// stringList is a repeatable flag.Value.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
 func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("no website provided\nUsage: <url> <max concurrency> <max pages to crawl>")
+	ignoreRobots := flag.Bool("ignore-robots", false, "don't fetch or obey robots.txt (only for audits of our own sites)")
+	gracePeriod := flag.Duration("grace-period", 10*time.Second, "how long in-flight requests may finish after an interrupt before the partial report is written")
+	checkpointFile := flag.String("checkpoint", "", "periodically save the crawl state to this file")
+	checkpointInterval := flag.Duration("checkpoint-interval", time.Minute, "how often to save the crawl state")
+	resume := flag.Bool("resume", false, "continue the crawl saved in the -checkpoint file")
+	fetchSettings := defaultFetcherSettings()
+	flag.DurationVar(&fetchSettings.connectTimeout, "connect-timeout", fetchSettings.connectTimeout, "max time to establish a connection, including the TLS handshake")
+	flag.DurationVar(&fetchSettings.readTimeout, "read-timeout", fetchSettings.readTimeout, "max time to wait for response headers or for any read of the body")
+	flag.DurationVar(&fetchSettings.totalTimeout, "timeout", fetchSettings.totalTimeout, "max time for a whole request, including reading the body")
+	flag.Int64Var(&fetchSettings.maxBodyBytes, "max-body-size", fetchSettings.maxBodyBytes, "max bytes read from a response; larger pages are truncated and flagged (0 for no limit)")
+	flag.IntVar(&fetchSettings.maxIdleConnsPerHost, "max-idle-conns-per-host", fetchSettings.maxIdleConnsPerHost, "keep-alive connections kept open per host")
+	flag.IntVar(&fetchSettings.maxRetries, "retries", fetchSettings.maxRetries, "times to retry a request after a connection error, timeout, 429 or 5xx")
+	flag.DurationVar(&fetchSettings.retryBaseDelay, "retry-delay", fetchSettings.retryBaseDelay, "wait before the first retry; doubled for every retry after it")
+	flag.DurationVar(&fetchSettings.retryMaxDelay, "retry-max-delay", fetchSettings.retryMaxDelay, "longest wait between retries, including waits asked for by Retry-After")
+	flag.Float64Var(&fetchSettings.requestsPerSecond, "rate", fetchSettings.requestsPerSecond, "max requests per second to each host (0 for no limit); slowed down automatically on 429s")
+	flag.DurationVar(&fetchSettings.minDelay, "min-delay", fetchSettings.minDelay, "min time between two requests to the same host")
+	skipSitemaps := flag.Bool("no-sitemaps", false, "don't seed the crawl from sitemap.xml files")
+	checkExternal := flag.Bool("check-external", false, "after the crawl, check that links to other sites still work, without crawling them")
+	externalConcurrency := flag.Int("external-concurrency", 4, "external links checked at the same time")
+	externalRate := flag.Float64("external-rate", 1, "max requests per second to each external host")
+	maxRedirectChain := flag.Int("max-redirect-chain", defaultMaxRedirectChain, "flag redirect chains with more hops than this in the redirects report")
+	normalizeRules := defaultNormalizeRules()
+	flag.BoolVar(&normalizeRules.keepQuery, "keep-query", normalizeRules.keepQuery, "treat URLs with different query strings as different pages")
+	stripParams := flag.String("strip-params", strings.Join(normalizeRules.stripParams, ","), "comma-separated query parameters dropped with -keep-query; * matches any characters")
+	flag.BoolVar(&normalizeRules.sortQuery, "sort-query", normalizeRules.sortQuery, "sort query parameters, so their order doesn't matter")
+	flag.BoolVar(&normalizeRules.keepPort, "keep-port", normalizeRules.keepPort, "treat URLs on different ports as different pages (default ports are always removed)")
+	flag.BoolVar(&normalizeRules.mergeWWW, "merge-www", normalizeRules.mergeWWW, "treat www.example.com and example.com as the same host")
+	caseSensitivePaths := flag.Bool("case-sensitive-paths", false, "lowercase only the host, not the path")
+	keepDotSegments := flag.Bool("keep-dot-segments", false, "don't resolve . and .. segments in paths")
+	keepEscapes := flag.Bool("keep-escapes", false, "don't normalize percent-encoding")
+	filter := urlFilter{}
+	flag.Var(&filter.includes, "include", "only crawl URLs matching this rule: prefix:/path, glob:/path/* or regex:pattern (repeatable; a bare path is a prefix; a glob * stays within one path segment, ** spans any number)")
+	flag.Var(&filter.excludes, "exclude", "don't crawl URLs matching this rule, written like -include (repeatable)")
+	listExcluded := flag.Bool("list-excluded", false, "list URLs skipped by -include and -exclude in the report")
+	extraSeeds := stringList{}
+	flag.Var(&extraSeeds, "seed", "another URL to start crawling from (repeatable)")
+	scopeMode := flag.String("scope", scopeHost, "hosts to crawl: host (only the seed URLs' hosts), domain (every subdomain of their domains) or allowlist (the seed hosts and -allow-host)")
+	allowedHosts := stringList{}
+	flag.Var(&allowedHosts, "allow-host", "another host to crawl with -scope allowlist (repeatable)")
+	trapSettings := defaultTrapSettings()
+	flag.IntVar(&trapSettings.maxPathSegments, "max-path-segments", trapSettings.maxPathSegments, "treat URLs with more path segments than this as a crawler trap (0 for no limit)")
+	flag.IntVar(&trapSettings.maxSegmentRepeats, "max-segment-repeats", trapSettings.maxSegmentRepeats, "treat URLs repeating a path segment more often than this as a crawler trap (0 for no limit)")
+	flag.IntVar(&trapSettings.maxPatternURLs, "max-pattern-urls", trapSettings.maxPatternURLs, "max URLs crawled that only differ by numbers, like calendar pages (0 for no limit)")
+	flag.IntVar(&trapSettings.maxQueryParams, "max-query-params", trapSettings.maxQueryParams, "treat URLs with more query parameters than this as a crawler trap (0 for no limit)")
+	flag.IntVar(&trapSettings.maxQueryVariants, "max-query-variants", trapSettings.maxQueryVariants, "max query strings crawled for the same path with -keep-query (0 for no limit)")
+	metaLimits := defaultPageMetaLimits()
+	flag.IntVar(&metaLimits.maxTitleLength, "max-title-length", metaLimits.maxTitleLength, "flag titles longer than this many characters (0 for no limit)")
+	flag.IntVar(&metaLimits.maxDescriptionLength, "max-description-length", metaLimits.maxDescriptionLength, "flag meta descriptions longer than this many characters (0 for no limit)")
+	obeyNofollow := flag.Bool("obey-nofollow", false, "don't follow rel=nofollow links or any link on pages marked nofollow by meta robots or X-Robots-Tag")
+	dedupeCanonical := flag.Bool("dedupe-canonical", false, "don't follow links on pages whose canonical URL is another page; crawl the canonical page instead")
+	selectorsFile := flag.String("selectors", "", "JSON file of extra fields to extract with CSS selectors, added as report columns")
+	maxDepth := flag.Int("max-depth", -1, "don't follow links on pages more than this many clicks from the base URL (-1 for no limit)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	normalizeRules.stripParams = strings.FieldsFunc(*stripParams, func(r rune) bool { return r == ',' })
+	normalizeRules.lowercasePath = !*caseSensitivePaths
+	normalizeRules.resolveDotSegments = !*keepDotSegments
+	normalizeRules.normalizeEscapes = !*keepEscapes
+	args := flag.Args()
+
+	if len(args) < 1 {
+		log.Fatal("no website provided\n" + usage)
 	}
-	if len(os.Args) > 4 {
-		log.Fatal("too many arguments provided\nUsage: <url> <max concurrency> <max pages to crawl>")
+	if len(args) > 3 {
+		log.Fatal("too many arguments provided\n" + usage)
 	}
-	rawBaseURL := os.Args[1]
+	rawBaseURL := args[0]
 	maxConcurrency := 3
 	maxPages := 1000
-	if len(os.Args) == 3 {
+	if len(args) >= 2 {
 		var err error
-		maxConcurrency, err = strconv.Atoi(os.Args[2])
+		maxConcurrency, err = strconv.Atoi(args[1])
 		if err != nil {
 			log.Fatal("Please provide a valid number for concurrency.")
 		}
-	} else if len(os.Args) == 4 {
+	}
+	if len(args) == 3 {
 		var err error
-		maxConcurrency, err = strconv.Atoi(os.Args[2])
+		maxPages, err = strconv.Atoi(args[2])
 		if err != nil {
-			log.Fatal("Please provide a valid number for concurrency.")
-		}
-		maxPages, err = strconv.Atoi(os.Args[3])
-		if err != nil {
-			log.Fatal("Please provide a valid number for concurrency.")
+			log.Fatal("Please provide a valid number for max pages.")
 		}
 	}
 
-	cfg, err := configure(rawBaseURL, maxConcurrency, maxPages)
+	cfg, err := configure(rawBaseURL, maxConcurrency, maxPages, *maxDepth, *ignoreRobots, fetchSettings)
 	if err != nil {
 		fmt.Printf("Error - configure: %v", err)
 		return
 	}
 
-	fmt.Printf("starting crawl of: %s...\nConcurrency: %d\nMax pages: %d\n", rawBaseURL, maxConcurrency, maxPages)
+	for _, rawSeed := range extraSeeds {
+		seed, err := parseSeedURL(rawSeed)
+		if err != nil {
+			log.Fatalf("invalid -seed %s: %v", rawSeed, err)
+		}
+		cfg.seeds = append(cfg.seeds, seed)
+	}
+	cfg.scope, err = newCrawlScope(*scopeMode, cfg.seeds, allowedHosts)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	cfg.normalizeRules = normalizeRules
+	cfg.filter = filter
+	cfg.obeyNofollow = *obeyNofollow
+	cfg.dedupeCanonical = *dedupeCanonical
+	selectorRules := []selectorRule{}
+	if *selectorsFile != "" {
+		selectorRules, err = loadSelectorRules(*selectorsFile)
+		if err != nil {
+			log.Fatalf("error loading selector rules: %v", err)
+		}
+		cfg.extractors = append(cfg.extractors, selectorRulesExtractor(selectorRules))
+	}
+	cfg.traps = newTrapDetector(trapSettings)
+	for _, seed := range cfg.seeds {
+		if reason := filter.excludedBy(seed); reason != "" {
+			log.Fatalf("the seed URL %s is out of scope: %s", seed, reason)
+		}
+	}
+	cfg.gracePeriod = *gracePeriod
+	cfg.maxRedirectChain = *maxRedirectChain
+	cfg.metaLimits = metaLimits
+	cfg.checkpointFile = *checkpointFile
+	cfg.checkpointInterval = *checkpointInterval
+
+	// The first SIGINT/SIGTERM stops the crawl and writes a partial report, a second one exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	if *resume {
+		if *checkpointFile == "" {
+			log.Fatal("-resume needs the -checkpoint file to resume from")
+		}
+		err = cfg.loadCheckpoint(*checkpointFile)
+		if err != nil {
+			log.Fatalf("error loading checkpoint: %v", err)
+		}
+		fmt.Printf("resuming crawl of: %s...\nPages so far: %d\n", rawBaseURL, len(cfg.pages))
+	} else {
+		fmt.Printf("starting crawl of: %s...\nConcurrency: %d\nMax pages: %d\n", rawBaseURL, maxConcurrency, maxPages)
+
+		for _, seed := range cfg.seeds {
+			cfg.enqueue(seed.String(), discoveredByLink, 0)
+		}
+
+		// Seed the crawl with every page listed in the site's sitemaps
+		if !*skipSitemaps {
+			sitemapURLs := cfg.collectSitemapURLs(ctx)
+			fmt.Printf("found %d URLs in sitemaps\n", len(sitemapURLs))
+			for _, sitemapURL := range sitemapURLs {
+				cfg.enqueueSitemapSeed(sitemapURL)
+			}
+		}
+	}
+
+	complete := cfg.crawl(ctx)
+	if !complete {
+		fmt.Printf("crawl interrupted, writing partial reports (flagged by %s)\n", filenameIncomplete)
+	}
+	cfg.applyLinkGraph()
+
+	reportPages := cfg.pages
+	if *listExcluded {
+		reportPages = make(map[string]PageData, len(cfg.pages)+len(cfg.excluded))
+		maps.Copy(reportPages, cfg.excluded)
+		maps.Copy(reportPages, cfg.pages)
+	}
+	err = writeIncompleteMarker(filenameIncomplete, complete)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	err = writeCSVReport(reportPages, filenameCSV, selectorRuleFields(selectorRules))
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	if *checkExternal && ctx.Err() == nil {
+		externalSettings := fetchSettings
+		externalSettings.requestsPerSecond = *externalRate
+		externalSettings.maxBodyBytes = maxExternalBodyBytes
+		externalSettings.maxRetries = min(fetchSettings.maxRetries, 1)
+		cfg.checkExternalLinks(ctx, newFetcher(externalSettings), *externalConcurrency)
+		err = writeExternalLinksReport(cfg.externalPages, cfg.links, filenameExternalLinksCSV)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		fmt.Printf("External links checked: %d (see %s)\n", len(cfg.externalPages), filenameExternalLinksCSV)
+	}
+
+	brokenLinks := cfg.brokenLinks()
+	err = writeBrokenLinksReport(brokenLinks, filenameBrokenLinksCSV)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	redirectedLinks := cfg.redirectedLinks()
+	err = writeRedirectsReport(redirectedLinks, filenameRedirectsCSV)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	nofollowLinks := cfg.nofollowLinks()
+	err = writeNofollowLinksReport(nofollowLinks, filenameNofollowLinksCSV)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	noindexedPages := cfg.noindexedPages()
+	err = writeNoindexedPagesReport(noindexedPages, filenameNoindexedPagesCSV)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	canonicalMismatches := cfg.canonicalMismatches()
+	err = writeCanonicalsReport(canonicalMismatches, filenameCanonicalsCSV)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
 
-	cfg.wg.Add(1)
-	go cfg.crawlPage(rawBaseURL)
-	cfg.wg.Wait()
+	pageMetaIssues := cfg.pageMetaIssues()
+	err = writePageMetaIssuesReport(pageMetaIssues, filenamePageMetaIssuesCSV)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
 
-	err = writeCSVReport(cfg.pages, filenameCSV)
+	schemaTypes := cfg.schemaTypes()
+	err = writeSchemaTypesReport(schemaTypes, filenameSchemaTypesCSV)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	jsonLDErrors := cfg.jsonLDErrors()
+	err = writeJSONLDErrorsReport(jsonLDErrors, filenameJSONLDErrorsCSV)
 	if err != nil {
 		log.Fatalf("error: %v", err)
 	}
 
+	links := cfg.allLinks()
+	err = writeLinksReport(links, filenameLinksCSV)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	emptyAnchors, genericAnchors := 0, 0
+	for _, link := range links {
+		switch link.AnchorIssue {
+		case anchorIssueEmpty:
+			emptyAnchors++
+		case anchorIssueGeneric:
+			genericAnchors++
+		}
+	}
+
 	for normalizedURL, pageData := range cfg.pages {
-		fmt.Printf("%d - %s\n", pageData.Visits, normalizedURL)
+		status := formatStatusCode(pageData.StatusCode)
+		if pageData.ErrorCategory != "" {
+			status += " " + pageData.ErrorCategory
+		}
+		fmt.Printf("%d - %s - %s\n", pageData.InboundLinks, normalizedURL, status)
 	}
 	fmt.Printf("Pages crawled: %d\n", len(cfg.pages))
+	if len(cfg.excluded) > 0 {
+		fmt.Printf("URLs out of scope: %d\n", len(cfg.excluded))
+	}
+	if len(cfg.traps.found) > 0 {
+		fmt.Printf("Crawler traps: %d URLs not crawled\n", len(cfg.traps.found))
+		for _, trap := range cfg.traps.summary() {
+			fmt.Printf("  %s %s: %d URLs, e.g. %s\n", trap.Reason, trap.Pattern, trap.Count, trap.Example)
+		}
+	}
+	fmt.Printf("Broken links: %d (see %s)\n", len(brokenLinks), filenameBrokenLinksCSV)
+	fmt.Printf("Links to redirects: %d (see %s)\n", len(redirectedLinks), filenameRedirectsCSV)
+	fmt.Printf("Internal nofollow links: %d (see %s)\n", len(nofollowLinks), filenameNofollowLinksCSV)
+	fmt.Printf("Noindexed pages: %d (see %s)\n", len(noindexedPages), filenameNoindexedPagesCSV)
+	fmt.Printf("Canonical mismatches: %d (see %s)\n", len(canonicalMismatches), filenameCanonicalsCSV)
+	fmt.Printf("Title and description issues: %d (see %s)\n", len(pageMetaIssues), filenamePageMetaIssuesCSV)
+	fmt.Printf("Schema types: %d (see %s)\n", len(schemaTypes), filenameSchemaTypesCSV)
+	fmt.Printf("Invalid JSON-LD blocks: %d (see %s)\n", len(jsonLDErrors), filenameJSONLDErrorsCSV)
+	fmt.Printf("Links: %d, %d with empty and %d with generic anchor text (see %s)\n", len(links), emptyAnchors, genericAnchors, filenameLinksCSV)
 }
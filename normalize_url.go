@@ -3,18 +3,165 @@ package main
 import (
 	"fmt"
 	net "net/url"
+	"path"
+	"slices"
 	"strings"
 )
 
-func normalizeURL(url string) (string, error) {
+// normalizeRules decide which URLs count as the same page. The defaults drop
+// the scheme, port and query and lowercase the whole URL.
+type normalizeRules struct {
+	// keepQuery keeps the query string, minus any parameter matching stripParams.
+	keepQuery bool
+	// stripParams are path.Match patterns for query parameters to drop, such as utm_*.
+	stripParams []string
+	// sortQuery sorts the kept query parameters, so their order doesn't matter.
+	sortQuery bool
+	// keepPort keeps the port unless it's the default one for the scheme.
+	keepPort bool
+	// mergeWWW treats www.example.com and example.com as the same host.
+	mergeWWW bool
+	// lowercasePath lowercases the path as well as the host.
+	lowercasePath bool
+	// resolveDotSegments removes "." and ".." segments and empty segments from the path.
+	resolveDotSegments bool
+	// normalizeEscapes decodes percent-encoded characters that don't need escaping
+	// and uppercases the hex digits of the rest.
+	normalizeEscapes bool
+}
+
+var defaultTrackingParams = []string{"utm_*", "fbclid", "gclid"}
+
+func defaultNormalizeRules() normalizeRules {
+	return normalizeRules{
+		stripParams:        defaultTrackingParams,
+		lowercasePath:      true,
+		resolveDotSegments: true,
+		normalizeEscapes:   true,
+	}
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// normalize reduces a URL to the key identifying its page: the host and path,
+// and whatever else the rules keep.
+func (r normalizeRules) normalize(url string) (string, error) {
 	urlObject, err := net.Parse(url)
 	if err != nil {
 		return "", fmt.Errorf("couldn't parse URL: %v", err)
 	}
-	normalizedUrl, err := net.JoinPath(urlObject.Hostname(), urlObject.Path)
-	if err != nil {
-		return "", fmt.Errorf("error joining host and path: %v", err)
+
+	host := r.normalizeHost(urlObject.Hostname())
+	if port := urlObject.Port(); r.keepPort && port != "" && port != defaultPorts[urlObject.Scheme] {
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		host += ":" + port
+	}
+
+	urlPath := urlObject.EscapedPath()
+	if r.normalizeEscapes {
+		urlPath = normalizeEscapes(urlPath)
+	}
+	if r.resolveDotSegments && urlPath != "" {
+		urlPath = path.Clean("/" + urlPath)
+	}
+	if r.lowercasePath {
+		urlPath = strings.ToLower(urlPath)
+	}
+	normalizedUrl := strings.TrimRight(host+urlPath, "/")
+
+	if r.keepQuery {
+		if query := r.normalizeQuery(urlObject.RawQuery); query != "" {
+			normalizedUrl += "?" + query
+		}
+	}
+	return normalizedUrl, nil
+}
+
+// normalizeHost lowercases a host name and drops a leading www. if www and
+// bare hosts are merged.
+func (r normalizeRules) normalizeHost(hostname string) string {
+	hostname = strings.ToLower(hostname)
+	if r.mergeWWW {
+		hostname = strings.TrimPrefix(hostname, "www.")
+	}
+	return hostname
+}
+
+// normalizeQuery drops empty and stripped parameters, keeping the rest as written.
+func (r normalizeRules) normalizeQuery(rawQuery string) string {
+	params := []string{}
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(param, "=")
+		if unescaped, err := net.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if r.isStripped(key) {
+			continue
+		}
+		if r.normalizeEscapes {
+			param = normalizeEscapes(param)
+		}
+		params = append(params, param)
+	}
+	if r.sortQuery {
+		slices.Sort(params)
+	}
+	return strings.Join(params, "&")
+}
+
+func (r normalizeRules) isStripped(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range r.stripParams {
+		if matched, _ := path.Match(strings.ToLower(pattern), key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeEscapes decodes percent-encoded unreserved characters (RFC 3986
+// section 2.3) and uppercases the hex digits of every other escape.
+func normalizeEscapes(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(strings.ToUpper(s[i : i+3]))
+		}
+		i += 2
 	}
-	normalizedUrl = strings.ToLower(normalizedUrl)
-	return strings.TrimRight(normalizedUrl, "/"), nil
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
 }
@@ -9,6 +9,7 @@ func TestNormalizeURL(t *testing.T) {
 	tests := []struct {
 		name          string
 		inputURL      string
+		rules         *normalizeRules
 		expected      string
 		errorContains string
 	}{
@@ -53,11 +54,74 @@ func TestNormalizeURL(t *testing.T) {
 			inputURL: "http://BLOG.boot.dev/path/",
 			expected: "blog.boot.dev/path",
 		},
+		{
+			name:     "resolve dot segments and empty segments",
+			inputURL: "https://blog.boot.dev/a/./b/../c//d/",
+			expected: "blog.boot.dev/a/c/d",
+		},
+		{
+			name:     "keep dot segments",
+			inputURL: "https://blog.boot.dev/a/./b/../c",
+			rules:    &normalizeRules{lowercasePath: true},
+			expected: "blog.boot.dev/a/./b/../c",
+		},
+		{
+			name:     "decode unreserved escapes and uppercase the rest",
+			inputURL: "https://blog.boot.dev/%7euser/a%2fb%c3%a9",
+			rules:    &normalizeRules{normalizeEscapes: true},
+			expected: "blog.boot.dev/~user/a%2Fb%C3%A9",
+		},
+		{
+			name:     "case-fold host only",
+			inputURL: "https://BLOG.boot.dev/Path/To/Page",
+			rules:    &normalizeRules{},
+			expected: "blog.boot.dev/Path/To/Page",
+		},
+		{
+			name:     "keep query and strip tracking params",
+			inputURL: "https://blog.boot.dev/path?page=2&utm_source=news&UTM_Medium=mail&fbclid=abc&gclid=def&q=go#top",
+			rules:    &normalizeRules{keepQuery: true, stripParams: defaultTrackingParams},
+			expected: "blog.boot.dev/path?page=2&q=go",
+		},
+		{
+			name:     "sort query params",
+			inputURL: "https://blog.boot.dev/path?b=2&a=1&&c=3",
+			rules:    &normalizeRules{keepQuery: true, sortQuery: true},
+			expected: "blog.boot.dev/path?a=1&b=2&c=3",
+		},
+		{
+			name:     "drop query that only had tracking params",
+			inputURL: "https://blog.boot.dev/path?utm_campaign=spring",
+			rules:    &normalizeRules{keepQuery: true, stripParams: defaultTrackingParams},
+			expected: "blog.boot.dev/path",
+		},
+		{
+			name:     "remove default port",
+			inputURL: "https://blog.boot.dev:443/path",
+			rules:    &normalizeRules{keepPort: true},
+			expected: "blog.boot.dev/path",
+		},
+		{
+			name:     "keep other ports",
+			inputURL: "http://blog.boot.dev:8080/path",
+			rules:    &normalizeRules{keepPort: true},
+			expected: "blog.boot.dev:8080/path",
+		},
+		{
+			name:     "merge www and bare host",
+			inputURL: "https://WWW.boot.dev/path",
+			rules:    &normalizeRules{mergeWWW: true},
+			expected: "boot.dev/path",
+		},
 	}
 
 	for i, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			actual, err := normalizeURL(tc.inputURL)
+			rules := defaultNormalizeRules()
+			if tc.rules != nil {
+				rules = *tc.rules
+			}
+			actual, err := rules.normalize(tc.inputURL)
 			if err != nil && tc.errorContains != "" {
 				if !strings.Contains(err.Error(), tc.errorContains) {
 					t.Errorf("\nTest %v - '%s' \nExpected error message to contain '%s'", i+1, tc.name, tc.errorContains)
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	goquery "github.com/PuerkitoBio/goquery"
+)
+
+// Heading is one <h1> to <h6> of a page, in document order.
+type Heading struct {
+	Level int
+	Text  string
+}
+
+// PageMeta is what a page says about itself in its <head> and headings.
+type PageMeta struct {
+	Title string
+	// TitleCount is how many <title> elements the page has; only the first one counts.
+	TitleCount      int
+	MetaDescription string
+	// MetaDescriptionCount is how many <meta name="description"> the page has.
+	MetaDescriptionCount int
+	MetaKeywords         string
+	Headings             []Heading
+}
+
+// pageMetaLimits are the lengths, in characters, above which titles and
+// descriptions are flagged as too long to show in full in search results.
+type pageMetaLimits struct {
+	maxTitleLength       int
+	maxDescriptionLength int
+}
+
+func defaultPageMetaLimits() pageMetaLimits {
+	return pageMetaLimits{
+		maxTitleLength:       60,
+		maxDescriptionLength: 160,
+	}
+}
+
+func extractPageMeta(page *ParsedPage, data *PageData) error {
+	data.PageMeta = pageMetaFromDocument(page.Doc)
+	return nil
+}
+
+func pageMetaFromDocument(doc *goquery.Document) PageMeta {
+	meta := PageMeta{}
+
+	// <title> inside an inline <svg> names the image, not the page
+	titles := doc.Find("title").Not("svg title")
+	meta.TitleCount = titles.Length()
+	meta.Title = collapseWhitespace(titles.First().Text())
+
+	doc.Find("meta[name][content]").Each(func(_ int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		content, _ := s.Attr("content")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "description":
+			if meta.MetaDescriptionCount == 0 {
+				meta.MetaDescription = collapseWhitespace(content)
+			}
+			meta.MetaDescriptionCount++
+		case "keywords":
+			if meta.MetaKeywords == "" {
+				meta.MetaKeywords = collapseWhitespace(content)
+			}
+		}
+	})
+
+	meta.Headings = []Heading{}
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(_ int, s *goquery.Selection) {
+		meta.Headings = append(meta.Headings, Heading{
+			Level: int(goquery.NodeName(s)[1] - '0'),
+			Text:  collapseWhitespace(s.Text()),
+		})
+	})
+	return meta
+}
+
+// headingCounts returns how many headings of each level there are, h1 first.
+func (m PageMeta) headingCounts() [6]int {
+	counts := [6]int{}
+	for _, heading := range m.Headings {
+		counts[heading.Level-1]++
+	}
+	return counts
+}
+
+// formatHeadingOutline writes headings as "h1 Title | h2 Section | ...".
+func formatHeadingOutline(headings []Heading) string {
+	outline := []string{}
+	for _, heading := range headings {
+		outline = append(outline, fmt.Sprintf("h%d %s", heading.Level, heading.Text))
+	}
+	return strings.Join(outline, " | ")
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func textLength(s string) int {
+	return utf8.RuneCountInString(s)
+}
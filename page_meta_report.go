@@ -0,0 +1,98 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+)
+
+const filenamePageMetaIssuesCSV = "page_meta_issues.csv"
+
+// Problems with a page's title or meta description.
+const (
+	metaIssueTitleMissing         = "title_missing"
+	metaIssueTitleDuplicate       = "title_duplicate"
+	metaIssueTitleTooLong         = "title_too_long"
+	metaIssueDescriptionMissing   = "description_missing"
+	metaIssueDescriptionDuplicate = "description_duplicate"
+	metaIssueDescriptionTooLong   = "description_too_long"
+)
+
+// pageMetaIssue is one problem found on one page. Value is the title or
+// description it is about, and Length its length in characters.
+type pageMetaIssue struct {
+	PageURL string
+	Issue   string
+	Value   string
+	Length  int
+}
+
+// issues lists the problems with a page's title and meta description.
+// Duplicates are repeated elements within the page itself.
+func (m PageMeta) issues(limits pageMetaLimits) []string {
+	issues := []string{}
+	switch {
+	case m.Title == "":
+		issues = append(issues, metaIssueTitleMissing)
+	case limits.maxTitleLength > 0 && textLength(m.Title) > limits.maxTitleLength:
+		issues = append(issues, metaIssueTitleTooLong)
+	}
+	if m.TitleCount > 1 {
+		issues = append(issues, metaIssueTitleDuplicate)
+	}
+	switch {
+	case m.MetaDescription == "":
+		issues = append(issues, metaIssueDescriptionMissing)
+	case limits.maxDescriptionLength > 0 && textLength(m.MetaDescription) > limits.maxDescriptionLength:
+		issues = append(issues, metaIssueDescriptionTooLong)
+	}
+	if m.MetaDescriptionCount > 1 {
+		issues = append(issues, metaIssueDescriptionDuplicate)
+	}
+	return issues
+}
+
+// pageMetaIssues lists the title and description problems of every HTML page
+// crawled, sorted by page URL.
+func (cfg *config) pageMetaIssues() []pageMetaIssue {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	result := []pageMetaIssue{}
+	for _, page := range cfg.pages {
+		// Only pages that were fetched and parsed have a <head> to check
+		if page.StatusCode == 0 || page.FetchError != "" {
+			continue
+		}
+		for _, issue := range page.PageMeta.issues(cfg.metaLimits) {
+			value := page.Title
+			if issue == metaIssueDescriptionMissing || issue == metaIssueDescriptionTooLong || issue == metaIssueDescriptionDuplicate {
+				value = page.MetaDescription
+			}
+			result = append(result, pageMetaIssue{
+				PageURL: page.URL,
+				Issue:   issue,
+				Value:   value,
+				Length:  textLength(value),
+			})
+		}
+	}
+
+	slices.SortFunc(result, func(a, b pageMetaIssue) int {
+		return cmp.Or(cmp.Compare(a.PageURL, b.PageURL), cmp.Compare(a.Issue, b.Issue))
+	})
+	return result
+}
+
+func writePageMetaIssuesReport(issues []pageMetaIssue, filename string) error {
+	rows := [][]string{}
+	for _, issue := range issues {
+		rows = append(rows, []string{
+			issue.PageURL,
+			issue.Issue,
+			issue.Value,
+			strconv.Itoa(issue.Length),
+		})
+	}
+	return writeCSV(filename, []string{"page_url", "issue", "value", "length"}, rows)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPageMetaFromHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected PageMeta
+	}{
+		{
+			name: "title, description, keywords and outline",
+			html: `<html><head>
+				<title>  Red   shoes | Shop </title>
+				<meta name="Description" content="Comfortable red shoes.">
+				<meta name="keywords" content="shoes, red">
+			</head><body>
+				<h1>Red shoes</h1>
+				<h2>Sizes</h2><h3>EU</h3><h3>US</h3>
+				<h2>Reviews <small>(12)</small></h2>
+			</body></html>`,
+			expected: PageMeta{
+				Title:                "Red shoes | Shop",
+				TitleCount:           1,
+				MetaDescription:      "Comfortable red shoes.",
+				MetaDescriptionCount: 1,
+				MetaKeywords:         "shoes, red",
+				Headings: []Heading{
+					{Level: 1, Text: "Red shoes"},
+					{Level: 2, Text: "Sizes"},
+					{Level: 3, Text: "EU"},
+					{Level: 3, Text: "US"},
+					{Level: 2, Text: "Reviews (12)"},
+				},
+			},
+		},
+		{
+			name: "repeated title and description, first one kept",
+			html: `<html><head>
+				<title>First</title><title>Second</title>
+				<meta name="description" content="One">
+				<meta name="description" content="Two">
+			</head><body></body></html>`,
+			expected: PageMeta{
+				Title:                "First",
+				TitleCount:           2,
+				MetaDescription:      "One",
+				MetaDescriptionCount: 2,
+				Headings:             []Heading{},
+			},
+		},
+		{
+			name: "svg title is not the page title",
+			html: `<html><head></head><body><svg><title>Logo</title></svg><h4>Footer</h4></body></html>`,
+			expected: PageMeta{
+				Headings: []Heading{{Level: 4, Text: "Footer"}},
+			},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := parsePage(tc.html, "https://example.com/")
+			if err != nil {
+				t.Fatalf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+			}
+			actual := pageMetaFromDocument(page.Doc)
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Errorf("Test %v - %s\nExpected: %+v\nActual: %+v", i+1, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestHeadingOutline(t *testing.T) {
+	meta := PageMeta{Headings: []Heading{{1, "Shop"}, {2, "Shoes"}, {2, "Bags"}, {6, "Legal"}}}
+	if counts := meta.headingCounts(); counts != [6]int{1, 2, 0, 0, 0, 1} {
+		t.Errorf("Expected counts [1 2 0 0 0 1], got %v", counts)
+	}
+	expected := "h1 Shop | h2 Shoes | h2 Bags | h6 Legal"
+	if outline := formatHeadingOutline(meta.Headings); outline != expected {
+		t.Errorf("Expected: %q\nActual: %q", expected, outline)
+	}
+}
+
+func TestPageMetaIssues(t *testing.T) {
+	limits := pageMetaLimits{maxTitleLength: 10, maxDescriptionLength: 20}
+	tests := []struct {
+		name     string
+		meta     PageMeta
+		expected []string
+	}{
+		{
+			name:     "no issues",
+			meta:     PageMeta{Title: "Shop", TitleCount: 1, MetaDescription: "Shoes and bags", MetaDescriptionCount: 1},
+			expected: []string{},
+		},
+		{
+			name:     "missing title and description",
+			meta:     PageMeta{},
+			expected: []string{metaIssueTitleMissing, metaIssueDescriptionMissing},
+		},
+		{
+			name:     "too long, counted in characters",
+			meta:     PageMeta{Title: "Ääkköset ja", TitleCount: 1, MetaDescription: strings.Repeat("ö", 21), MetaDescriptionCount: 1},
+			expected: []string{metaIssueTitleTooLong, metaIssueDescriptionTooLong},
+		},
+		{
+			name:     "exactly at the limit",
+			meta:     PageMeta{Title: "Ääkköset j", TitleCount: 1, MetaDescription: strings.Repeat("ö", 20), MetaDescriptionCount: 1},
+			expected: []string{},
+		},
+		{
+			name:     "duplicated within the page",
+			meta:     PageMeta{Title: "Shop", TitleCount: 2, MetaDescription: "Shoes", MetaDescriptionCount: 3},
+			expected: []string{metaIssueTitleDuplicate, metaIssueDescriptionDuplicate},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := tc.meta.issues(limits)
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Errorf("Test %v - %s\nExpected: %v\nActual: %v", i+1, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestConfigPageMetaIssues(t *testing.T) {
+	cfg, err := configure("https://example.com", 1, 10, -1, true, defaultFetcherSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.pages["example.com/b"] = PageData{
+		URL:         "https://example.com/b",
+		FetchRecord: FetchRecord{StatusCode: 200},
+		PageMeta:    PageMeta{Title: "B", TitleCount: 1, MetaDescription: "A very long description", MetaDescriptionCount: 1},
+	}
+	cfg.pages["example.com/a"] = PageData{
+		URL:         "https://example.com/a",
+		FetchRecord: FetchRecord{StatusCode: 200},
+		PageMeta:    PageMeta{Title: "A", TitleCount: 2},
+	}
+	// Pages that weren't parsed have no title to check
+	cfg.pages["example.com/missing"] = PageData{
+		URL:         "https://example.com/missing",
+		FetchRecord: FetchRecord{StatusCode: 404, FetchError: "error (404)"},
+	}
+	cfg.pages["example.com/private"] = PageData{URL: "https://example.com/private", SkipReason: robotsBlockedReason}
+	cfg.metaLimits = pageMetaLimits{maxTitleLength: 60, maxDescriptionLength: 10}
+
+	expected := []pageMetaIssue{
+		{PageURL: "https://example.com/a", Issue: metaIssueDescriptionMissing, Value: "", Length: 0},
+		{PageURL: "https://example.com/a", Issue: metaIssueTitleDuplicate, Value: "A", Length: 1},
+		{PageURL: "https://example.com/b", Issue: metaIssueDescriptionTooLong, Value: "A very long description", Length: 23},
+	}
+	actual := cfg.pageMetaIssues()
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected: %+v\nActual: %+v", expected, actual)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxHostInterval caps how far a host's request interval is widened when it pushes back.
+const maxHostInterval = time.Minute
+
+type hostState struct {
+	// next is the earliest time the next request to the host may start
+	next        time.Time
+	interval    time.Duration
+	minInterval time.Duration
+	avgLatency  time.Duration
+}
+
+// hostLimiter spaces out requests to each host. The interval starts from the
+// configured rate and min delay (or a robots.txt Crawl-delay if that's longer),
+// widens when the host answers with 429/503 or suddenly gets slow, and drifts
+// back down while requests go through fine.
+type hostLimiter struct {
+	mu           sync.Mutex
+	hosts        map[string]*hostState
+	baseInterval time.Duration
+	// backoffStep is the smallest interval used once a host has pushed back
+	backoffStep time.Duration
+}
+
+func newHostLimiter(requestsPerSecond float64, minDelay, backoffStep time.Duration) *hostLimiter {
+	baseInterval := minDelay
+	if requestsPerSecond > 0 {
+		baseInterval = max(baseInterval, time.Duration(float64(time.Second)/requestsPerSecond))
+	}
+	return &hostLimiter{
+		hosts:        make(map[string]*hostState),
+		baseInterval: baseInterval,
+		backoffStep:  backoffStep,
+	}
+}
+
+// pushBackNext makes a widened interval apply to the very next request rather
+// than the one after it.
+func (s *hostState) pushBackNext() {
+	if next := time.Now().Add(s.interval); next.After(s.next) {
+		s.next = next
+	}
+}
+
+// state must be called with l.mu held.
+func (l *hostLimiter) state(host string) *hostState {
+	state, ok := l.hosts[host]
+	if !ok {
+		state = &hostState{interval: l.baseInterval, minInterval: l.baseInterval}
+		l.hosts[host] = state
+	}
+	return state
+}
+
+// wait blocks until a request to host may be sent, or until ctx is done.
+// Each caller reserves its own slot, so concurrent workers queue up behind
+// each other instead of all firing once the interval passes.
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	state := l.state(host)
+	start := time.Now()
+	if state.next.After(start) {
+		start = state.next
+	}
+	state.next = start.Add(state.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// setMinDelay raises the smallest interval allowed for host, as asked for by a
+// robots.txt Crawl-delay.
+func (l *hostLimiter) setMinDelay(host string, delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state := l.state(host)
+	state.minInterval = max(l.baseInterval, delay)
+	state.interval = max(state.interval, state.minInterval)
+}
+
+// observe adjusts the host's interval after a response. latency is the time
+// until the response headers arrived.
+func (l *hostLimiter) observe(host string, statusCode int, latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state := l.state(host)
+
+	slowdown := state.avgLatency > 0 && latency > 3*state.avgLatency
+	switch {
+	case statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable:
+		l.backOff(state)
+	case slowdown:
+		state.interval = min(max(state.interval*3/2, l.backoffStep/2), maxHostInterval)
+		state.pushBackNext()
+	default:
+		state.interval = max(state.interval*9/10, state.minInterval)
+	}
+
+	if state.avgLatency == 0 {
+		state.avgLatency = latency
+	} else {
+		state.avgLatency = (state.avgLatency*4 + latency) / 5
+	}
+}
+
+// observeError widens the host's interval after a timeout or a dropped
+// connection, which is pushing back as much as a 429 is.
+func (l *hostLimiter) observeError(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.backOff(l.state(host))
+}
+
+// backOff must be called with l.mu held.
+func (l *hostLimiter) backOff(state *hostState) {
+	state.interval = min(max(2*state.interval, l.backoffStep), maxHostInterval)
+	state.pushBackNext()
+}
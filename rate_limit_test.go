@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterSpacing(t *testing.T) {
+	limiter := newHostLimiter(20, 0, time.Second)
+
+	start := time.Now()
+	for range 4 {
+		if err := limiter.wait(context.Background(), "example.com"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected 4 requests at 20/s to take at least 150ms, took %v", elapsed)
+	}
+
+	// Other hosts have their own budget
+	start = time.Now()
+	if err := limiter.wait(context.Background(), "other.com"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("Expected the first request to another host to go straight through, took %v", elapsed)
+	}
+}
+
+func TestHostLimiterObserve(t *testing.T) {
+	tests := []struct {
+		name     string
+		minDelay time.Duration
+		observe  func(l *hostLimiter)
+		expected time.Duration
+	}{
+		{
+			name:     "429 widens the interval to the backoff step",
+			observe:  func(l *hostLimiter) { l.observe("a", http.StatusTooManyRequests, time.Millisecond) },
+			expected: time.Second,
+		},
+		{
+			name: "repeated 503s keep doubling",
+			observe: func(l *hostLimiter) {
+				for range 3 {
+					l.observe("a", http.StatusServiceUnavailable, time.Millisecond)
+				}
+			},
+			expected: 4 * time.Second,
+		},
+		{
+			name: "timeouts and dropped connections keep doubling",
+			observe: func(l *hostLimiter) {
+				l.observeError("a")
+				l.observeError("a")
+			},
+			expected: 2 * time.Second,
+		},
+		{
+			name: "sudden slowdown widens the interval",
+			observe: func(l *hostLimiter) {
+				l.observe("a", http.StatusOK, 10*time.Millisecond)
+				l.observe("a", http.StatusOK, 100*time.Millisecond)
+			},
+			expected: 500 * time.Millisecond,
+		},
+		{
+			name: "success relaxes back towards the min delay",
+			observe: func(l *hostLimiter) {
+				l.observe("a", http.StatusTooManyRequests, time.Millisecond)
+				for range 100 {
+					l.observe("a", http.StatusOK, time.Millisecond)
+				}
+			},
+			expected: 100 * time.Millisecond,
+		},
+		{
+			name:     "crawl delay raises the min delay",
+			minDelay: 100 * time.Millisecond,
+			observe:  func(l *hostLimiter) { l.setMinDelay("a", 2*time.Second) },
+			expected: 2 * time.Second,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := newHostLimiter(10, tc.minDelay, time.Second)
+			tc.observe(limiter)
+			if actual := limiter.hosts["a"].interval; actual != tc.expected {
+				t.Errorf("Test %v - %s\nExpected: %v\nActual: %v", i+1, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
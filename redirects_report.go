@@ -0,0 +1,93 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+)
+
+const filenameRedirectsCSV = "redirects.csv"
+
+// defaultMaxRedirectChain is the number of hops above which a redirect chain
+// is flagged as too long.
+const defaultMaxRedirectChain = 3
+
+// Issues listed in the redirects report.
+const (
+	redirectIssueRedirect  = "redirect"
+	redirectIssueLongChain = "long_chain"
+	redirectIssueLoop      = "loop"
+)
+
+// redirectedLink is a link from a crawled page to an internal URL that
+// redirects, which should be updated to point at the final destination.
+type redirectedLink struct {
+	SourceURL     string
+	LinkURL       string
+	AnchorText    string
+	Hops          int
+	FinalURL      string
+	RedirectChain string
+	Issue         string
+}
+
+// redirectIssue reports what's wrong with how a page redirects, or "" if it doesn't.
+func redirectIssue(page PageData, maxChain int) string {
+	switch {
+	case page.ErrorCategory == errorCategoryRedirectLoop:
+		return redirectIssueLoop
+	case len(page.Redirects) > maxChain:
+		return redirectIssueLongChain
+	case len(page.Redirects) > 0:
+		return redirectIssueRedirect
+	}
+	return ""
+}
+
+// redirectedLinks lists every link pointing at a crawled page that redirects,
+// sorted by source page and then by link URL.
+func (cfg *config) redirectedLinks() []redirectedLink {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	result := []redirectedLink{}
+	for normalizedURL, page := range cfg.pages {
+		issue := redirectIssue(page, cfg.maxRedirectChain)
+		if issue == "" {
+			continue
+		}
+		for _, i := range cfg.links.inbound[normalizedURL] {
+			edge := cfg.links.edges[i]
+			result = append(result, redirectedLink{
+				SourceURL:     cfg.pageURL(edge.Source),
+				LinkURL:       edge.TargetURL,
+				AnchorText:    edge.AnchorText,
+				Hops:          len(page.Redirects),
+				FinalURL:      page.FinalURL,
+				RedirectChain: formatRedirects(page.Redirects, page.FinalURL),
+				Issue:         issue,
+			})
+		}
+	}
+
+	slices.SortFunc(result, func(a, b redirectedLink) int {
+		return cmp.Or(cmp.Compare(a.SourceURL, b.SourceURL), cmp.Compare(a.LinkURL, b.LinkURL))
+	})
+	return result
+}
+
+func writeRedirectsReport(links []redirectedLink, filename string) error {
+	rows := [][]string{}
+	for _, link := range links {
+		rows = append(rows, []string{
+			link.SourceURL,
+			link.LinkURL,
+			link.AnchorText,
+			strconv.Itoa(link.Hops),
+			link.FinalURL,
+			link.RedirectChain,
+			link.Issue,
+		})
+	}
+	return writeCSV(filename, []string{"source_page_url", "link_url", "anchor_text", "hops", "final_url", "redirect_chain", "issue"}, rows)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRedirectedLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>
+				<a href="/new">New</a>
+				<a href="/moved">Moved</a>
+				<a href="/chain">Chain</a>
+				<a href="/loop">Loop</a>
+			</body></html>`)
+		case "/new":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html><body><h1>New</h1></body></html>")
+		case "/moved":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/chain":
+			http.Redirect(w, r, "/chain2", http.StatusMovedPermanently)
+		case "/chain2":
+			http.Redirect(w, r, "/moved", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop2", http.StatusMovedPermanently)
+		case "/loop2":
+			http.Redirect(w, r, "/loop", http.StatusMovedPermanently)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	settings := testFetcherSettings()
+	settings.maxRetries = 0
+	cfg, err := configure(server.URL, 2, 100, -1, true, settings)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg.maxRedirectChain = 2
+	cfg.enqueue(server.URL+"/", discoveredByLink, 0)
+	cfg.crawl(context.Background())
+
+	expected := []redirectedLink{
+		{
+			SourceURL:     server.URL + "/",
+			LinkURL:       server.URL + "/chain",
+			AnchorText:    "Chain",
+			Hops:          3,
+			FinalURL:      server.URL + "/new",
+			RedirectChain: fmt.Sprintf("301 %[1]s/chain -> 302 %[1]s/chain2 -> 301 %[1]s/moved -> %[1]s/new", server.URL),
+			Issue:         redirectIssueLongChain,
+		},
+		{
+			SourceURL:     server.URL + "/",
+			LinkURL:       server.URL + "/loop",
+			AnchorText:    "Loop",
+			Hops:          2,
+			RedirectChain: fmt.Sprintf("301 %[1]s/loop -> 301 %[1]s/loop2 -> /loop", server.URL),
+			Issue:         redirectIssueLoop,
+		},
+		{
+			SourceURL:     server.URL + "/",
+			LinkURL:       server.URL + "/moved",
+			AnchorText:    "Moved",
+			Hops:          1,
+			FinalURL:      server.URL + "/new",
+			RedirectChain: fmt.Sprintf("301 %[1]s/moved -> %[1]s/new", server.URL),
+			Issue:         redirectIssueRedirect,
+		},
+	}
+
+	actual := cfg.redirectedLinks()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %+v\nActual: %+v", expected, actual)
+	}
+	if category := cfg.pages[cfg.baseURL.Hostname()+"/loop"].ErrorCategory; category != errorCategoryRedirectLoop {
+		t.Errorf("Expected the loop to be categorized as %s, got %q", errorCategoryRedirectLoop, category)
+	}
+}
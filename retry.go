@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// isRetryableStatus reports whether a response status is worth another attempt.
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// isTransientError reports whether a request error looks like a network hiccup,
+// such as a timeout, a refused or reset connection or a failed DNS lookup, as
+// opposed to a problem that won't go away like a malformed URL.
+func isTransientError(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, errReadTimeout) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// backoffDelay returns the wait before retry number attempt (starting at 1):
+// exponential growth from the base delay, capped at the max delay, with the
+// upper half randomized so parallel workers don't retry in lockstep. A base
+// delay of 0 retries right away.
+func backoffDelay(attempt int, base, maxDelay time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	// Compare before shifting so a large attempt can't overflow
+	delay := maxDelay
+	if shift := attempt - 1; base <= maxDelay>>shift {
+		delay = base << shift
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// getWithRetry calls get until it succeeds, fails with a non-transient error or
+// runs out of retries. A Retry-After header replaces the backoff delay, unless it
+// asks for more than the max delay, in which case we give up straight away.
+// The result always carries the number of attempts made.
+func (f *fetcher) getWithRetry(ctx context.Context, rawURL string) (fetchResult, error) {
+	return f.doWithRetry(ctx, "GET", rawURL)
+}
+
+// doWithRetry is getWithRetry with any request method.
+func (f *fetcher) doWithRetry(ctx context.Context, method, rawURL string) (fetchResult, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := f.do(ctx, method, rawURL)
+		result.attempts = attempt
+
+		retryable := false
+		if err != nil {
+			retryable = isTransientError(err) && ctx.Err() == nil
+		} else {
+			retryable = isRetryableStatus(result.res.StatusCode)
+		}
+		if !retryable || attempt > f.settings.maxRetries {
+			return result, err
+		}
+
+		delay := backoffDelay(attempt, f.settings.retryBaseDelay, f.settings.retryMaxDelay)
+		if err == nil {
+			if retryAfter, ok := parseRetryAfter(result.res.Header.Get("Retry-After"), time.Now()); ok {
+				if retryAfter > f.settings.retryMaxDelay {
+					return result, nil
+				}
+				delay = retryAfter
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return result, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		header   string
+		expected time.Duration
+		ok       bool
+	}{
+		{
+			name:     "seconds",
+			header:   "120",
+			expected: 2 * time.Minute,
+			ok:       true,
+		},
+		{
+			name:     "http date",
+			header:   "Mon, 01 Jan 2024 12:00:30 GMT",
+			expected: 30 * time.Second,
+			ok:       true,
+		},
+		{
+			name:     "date in the past",
+			header:   "Mon, 01 Jan 2024 11:00:00 GMT",
+			expected: 0,
+			ok:       true,
+		},
+		{
+			name:   "missing",
+			header: "",
+		},
+		{
+			name:   "garbage",
+			header: "soon",
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, ok := parseRetryAfter(tc.header, now)
+			if actual != tc.expected || ok != tc.ok {
+				t.Errorf("Test %v - %s\nExpected: %v, %v\nActual: %v, %v", i+1, tc.name, tc.expected, tc.ok, actual, ok)
+			}
+		})
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name        string
+		attempt     int
+		base        time.Duration
+		maxDelay    time.Duration
+		expectedMin time.Duration
+		expectedMax time.Duration
+	}{
+		{
+			name:        "first retry",
+			attempt:     1,
+			base:        time.Second,
+			maxDelay:    time.Minute,
+			expectedMin: 500 * time.Millisecond,
+			expectedMax: time.Second,
+		},
+		{
+			name:        "doubles every retry",
+			attempt:     3,
+			base:        time.Second,
+			maxDelay:    time.Minute,
+			expectedMin: 2 * time.Second,
+			expectedMax: 4 * time.Second,
+		},
+		{
+			name:        "capped at the max delay",
+			attempt:     10,
+			base:        time.Second,
+			maxDelay:    time.Minute,
+			expectedMin: 30 * time.Second,
+			expectedMax: time.Minute,
+		},
+		{
+			name:        "capped instead of overflowing",
+			attempt:     100,
+			base:        time.Second,
+			maxDelay:    time.Minute,
+			expectedMin: 30 * time.Second,
+			expectedMax: time.Minute,
+		},
+		{
+			name:     "zero base retries right away",
+			attempt:  3,
+			base:     0,
+			maxDelay: time.Minute,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := backoffDelay(tc.attempt, tc.base, tc.maxDelay)
+			if actual < tc.expectedMin || actual > tc.expectedMax {
+				t.Errorf("Test %v - %s\nExpected between %v and %v\nActual: %v", i+1, tc.name, tc.expectedMin, tc.expectedMax, actual)
+			}
+		})
+	}
+}
+
+func TestGetWithRetry(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int32
+		failStatus       int
+		retryAfter       string
+		expectedAttempts int
+		expectedStatus   int
+	}{
+		{
+			name:             "succeeds first time",
+			expectedAttempts: 1,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:             "recovers after 503s",
+			failures:         2,
+			failStatus:       http.StatusServiceUnavailable,
+			expectedAttempts: 3,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:             "honors short Retry-After",
+			failures:         1,
+			failStatus:       http.StatusTooManyRequests,
+			retryAfter:       "0",
+			expectedAttempts: 2,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:             "gives up on long Retry-After",
+			failures:         1,
+			failStatus:       http.StatusTooManyRequests,
+			retryAfter:       "3600",
+			expectedAttempts: 1,
+			expectedStatus:   http.StatusTooManyRequests,
+		},
+		{
+			name:             "runs out of retries",
+			failures:         10,
+			failStatus:       http.StatusBadGateway,
+			expectedAttempts: 4,
+			expectedStatus:   http.StatusBadGateway,
+		},
+		{
+			name:             "doesn't retry a 404",
+			failures:         10,
+			failStatus:       http.StatusNotFound,
+			expectedAttempts: 1,
+			expectedStatus:   http.StatusNotFound,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) <= tc.failures {
+					if tc.retryAfter != "" {
+						w.Header().Set("Retry-After", tc.retryAfter)
+					}
+					w.WriteHeader(tc.failStatus)
+					return
+				}
+				w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			settings := testFetcherSettings()
+			settings.maxRetries = 3
+			settings.retryBaseDelay = time.Millisecond
+			settings.retryMaxDelay = 10 * time.Millisecond
+			settings.backoffStep = time.Millisecond
+			result, err := newFetcher(settings).getWithRetry(context.Background(), server.URL)
+			if err != nil {
+				t.Fatalf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+			}
+			if result.attempts != tc.expectedAttempts || result.res.StatusCode != tc.expectedStatus {
+				t.Errorf("Test %v - %s\nExpected %d attempts, status %d\nActual: %d, %d", i+1, tc.name, tc.expectedAttempts, tc.expectedStatus, result.attempts, result.res.StatusCode)
+			}
+		})
+	}
+
+	t.Run("connection errors are retried", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		closedURL := server.URL
+		server.Close()
+
+		settings := testFetcherSettings()
+		settings.maxRetries = 2
+		settings.retryBaseDelay = time.Millisecond
+		settings.retryMaxDelay = time.Millisecond
+		settings.backoffStep = time.Millisecond
+		result, err := newFetcher(settings).getWithRetry(context.Background(), closedURL)
+		if err == nil {
+			t.Fatalf("Expected an error from a closed server")
+		}
+		if result.attempts != 3 {
+			t.Errorf("Expected 3 attempts, got %d", result.attempts)
+		}
+	})
+}
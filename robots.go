@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	robotsBlockedReason     = "blocked by robots.txt"
+	robotsUnreachableReason = "robots.txt unreachable"
+)
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+type robotsGroup struct {
+	userAgents []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsTxt struct {
+	groups   []*robotsGroup
+	sitemaps []string
+	// disallowAll is set when robots.txt couldn't be fetched because of a server error.
+	disallowAll bool
+	// unreachable is how fetching robots.txt failed when the host didn't
+	// answer at all, as with a refused connection or a DNS error.
+	unreachable *FetchRecord
+}
+
+// robotsUnreachableError is returned for pages on a host whose robots.txt
+// couldn't be fetched. That says nothing about what the site allows, but
+// the page itself can't be fetched either.
+type robotsUnreachableError struct {
+	record FetchRecord
+}
+
+func (e *robotsUnreachableError) Error() string {
+	return "robots.txt unreachable: " + e.record.FetchError
+}
+
+// parseRobotsTxt parses the body of a robots.txt file into user-agent groups.
+// Consecutive User-agent lines start a single group, as per RFC 9309.
+func parseRobotsTxt(body string) *robotsTxt {
+	robots := &robotsTxt{}
+	var current *robotsGroup
+	lastWasAgent := false
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if current == nil || !lastWasAgent {
+				current = &robotsGroup{}
+				robots.groups = append(robots.groups, current)
+			}
+			current.userAgents = append(current.userAgents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			// An empty Disallow means "allow everything", so it adds no rule.
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if current != nil {
+				seconds, err := strconv.ParseFloat(value, 64)
+				if err == nil && seconds > 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			if value != "" {
+				robots.sitemaps = append(robots.sitemaps, value)
+			}
+		}
+		lastWasAgent = false
+	}
+
+	return robots
+}
+
+// productToken returns the name part of a user agent, lowercased:
+// "bootcrawler" for "BootCrawler/1.0".
+func productToken(userAgent string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(userAgent, "/")[0]))
+}
+
+// groupFor returns the rules that apply to userAgent. Groups naming its
+// product token exactly are merged; if none match, the "*" groups are used
+// instead.
+func (r *robotsTxt) groupFor(userAgent string) *robotsGroup {
+	token := productToken(userAgent)
+	merged := &robotsGroup{}
+	wildcard := &robotsGroup{}
+	matched := false
+
+	for _, group := range r.groups {
+		for _, agent := range group.userAgents {
+			if agent == "*" {
+				mergeRobotsGroup(wildcard, group)
+				break
+			}
+			// An empty User-agent line names nobody
+			if agent != "" && agent == token {
+				mergeRobotsGroup(merged, group)
+				matched = true
+				break
+			}
+		}
+	}
+
+	if matched {
+		return merged
+	}
+	return wildcard
+}
+
+func mergeRobotsGroup(dst, src *robotsGroup) {
+	dst.userAgents = append(dst.userAgents, src.userAgents...)
+	dst.rules = append(dst.rules, src.rules...)
+	if src.crawlDelay > dst.crawlDelay {
+		dst.crawlDelay = src.crawlDelay
+	}
+}
+
+// allowed reports whether path (including any query string) may be fetched.
+// The longest matching rule wins, and Allow wins a tie.
+func (g *robotsGroup) allowed(path string) bool {
+	bestLength := -1
+	allow := true
+	for _, rule := range g.rules {
+		if !robotsPatternMatches(rule.pattern, path) {
+			continue
+		}
+		length := len(rule.pattern)
+		if length > bestLength || (length == bestLength && rule.allow) {
+			bestLength = length
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// robotsPatternMatches matches a robots.txt path pattern, where '*' matches any
+// sequence of characters and a trailing '$' anchors the pattern at the end.
+func robotsPatternMatches(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return strings.HasSuffix(path[pos:], part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+
+	if anchored {
+		return pos == len(path)
+	}
+	return true
+}
+
+type robotsEntry struct {
+	mu sync.Mutex
+	// robots is nil until robots.txt has been fetched without being canceled
+	robots *robotsTxt
+}
+
+// robotsCache fetches robots.txt once per host and keeps it for the whole run.
+// A Crawl-delay is handed over to the fetcher's rate limiter.
+type robotsCache struct {
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+	fetcher *fetcher
+}
+
+func newRobotsCache(f *fetcher) *robotsCache {
+	return &robotsCache{
+		entries: make(map[string]*robotsEntry),
+		fetcher: f,
+	}
+}
+
+// get returns the robots.txt of pageURL's host, fetching it on first use.
+// A fetch cut short by ctx isn't kept, so the next call tries again, and
+// ctx's error is returned.
+func (c *robotsCache) get(ctx context.Context, pageURL *url.URL) (*robotsTxt, error) {
+	key := pageURL.Scheme + "://" + pageURL.Host
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &robotsEntry{}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.robots != nil {
+		return entry.robots, nil
+	}
+	robots, err := fetchRobotsTxt(ctx, c.fetcher, key+"/robots.txt")
+	if ctx.Err() != nil {
+		return robots, ctx.Err()
+	}
+	if err != nil {
+		fmt.Printf("Error - fetchRobotsTxt: %v\n", err)
+	}
+	entry.robots = robots
+	c.fetcher.limiter.setMinDelay(pageURL.Host, robots.groupFor(userAgent).crawlDelay)
+	return robots, nil
+}
+
+// allowed reports whether pageURL may be crawled according to its host's
+// robots.txt. It returns ctx's error if robots.txt couldn't be fetched because
+// ctx was done, and a *robotsUnreachableError if the host didn't answer.
+func (c *robotsCache) allowed(ctx context.Context, pageURL *url.URL) (bool, error) {
+	robots, err := c.get(ctx, pageURL)
+	if err != nil {
+		return false, err
+	}
+	if robots.unreachable != nil {
+		return false, &robotsUnreachableError{record: *robots.unreachable}
+	}
+	if robots.disallowAll {
+		return false, nil
+	}
+	return robots.groupFor(userAgent).allowed(pageURL.RequestURI()), nil
+}
+
+// fetchRobotsTxt downloads and parses a robots.txt file. A missing file allows
+// everything, while a server error disallows everything until the next run.
+// A host that doesn't answer at all is marked unreachable.
+func fetchRobotsTxt(ctx context.Context, f *fetcher, robotsURL string) (*robotsTxt, error) {
+	result, err := f.getWithRetry(ctx, robotsURL)
+	if err != nil {
+		record := newFetchRecord(result, err)
+		return &robotsTxt{unreachable: &record}, fmt.Errorf("couldn't fetch %s: %v", robotsURL, err)
+	}
+
+	if result.res.StatusCode >= 500 {
+		return &robotsTxt{disallowAll: true}, fmt.Errorf("error (%d) getting %s", result.res.StatusCode, robotsURL)
+	}
+	if result.res.StatusCode >= 400 {
+		return &robotsTxt{}, nil
+	}
+	return parseRobotsTxt(string(result.body)), nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+
+	goquery "github.com/PuerkitoBio/goquery"
+)
+
+// robotsToken is the name we answer to in user-agent specific directives,
+// such as <meta name="bootcrawler"> or "X-Robots-Tag: bootcrawler: noindex".
+var robotsToken = productToken(userAgent)
+
+// RobotsDirectives are the rules a page sets for crawlers through
+// <meta name="robots"> or the X-Robots-Tag header.
+type RobotsDirectives struct {
+	NoIndex   bool
+	NoFollow  bool
+	NoArchive bool
+}
+
+// add applies a comma-separated list of directives such as "noindex, nofollow".
+// Directives we don't act on are ignored.
+func (d *RobotsDirectives) add(value string) {
+	for _, directive := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "noindex":
+			d.NoIndex = true
+		case "nofollow":
+			d.NoFollow = true
+		case "noarchive":
+			d.NoArchive = true
+		case "none":
+			d.NoIndex = true
+			d.NoFollow = true
+		}
+	}
+}
+
+// merge combines directives from two sources. The strictest one wins.
+func (d RobotsDirectives) merge(other RobotsDirectives) RobotsDirectives {
+	return RobotsDirectives{
+		NoIndex:   d.NoIndex || other.NoIndex,
+		NoFollow:  d.NoFollow || other.NoFollow,
+		NoArchive: d.NoArchive || other.NoArchive,
+	}
+}
+
+func robotsDirectivesFromDocument(doc *goquery.Document) RobotsDirectives {
+	directives := RobotsDirectives{}
+	doc.Find("meta[name][content]").Each(func(_ int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "robots" || name == robotsToken {
+			content, _ := s.Attr("content")
+			directives.add(content)
+		}
+	})
+	return directives
+}
+
+// Directives that take a value after a colon, which mustn't be mistaken for a
+// user agent name.
+var valuedRobotsDirectives = map[string]bool{
+	"unavailable_after": true,
+	"max-snippet":       true,
+	"max-image-preview": true,
+	"max-video-preview": true,
+}
+
+// parseXRobotsTag reads the values of every X-Robots-Tag header. A value
+// starting with a user agent name, as in "googlebot: noindex", only counts if
+// it names us.
+func parseXRobotsTag(values []string) RobotsDirectives {
+	directives := RobotsDirectives{}
+	for _, value := range values {
+		if name, rest, found := strings.Cut(value, ":"); found {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if !strings.Contains(name, ",") && !valuedRobotsDirectives[name] {
+				if name != robotsToken {
+					continue
+				}
+				value = rest
+			}
+		}
+		directives.add(value)
+	}
+	return directives
+}
+
+// hasRel reports whether a link's rel attribute contains token.
+func hasRel(rel, token string) bool {
+	for _, value := range strings.Fields(rel) {
+		if value == token {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+const (
+	filenameNofollowLinksCSV  = "nofollow_links.csv"
+	filenameNoindexedPagesCSV = "noindexed_pages.csv"
+)
+
+// nofollowLink is a link between two pages of the crawled site marked rel=nofollow.
+type nofollowLink struct {
+	SourceURL  string
+	LinkURL    string
+	AnchorText string
+	Rel        string
+}
+
+// nofollowLinks lists every internal link marked rel=nofollow, sorted by source
+// page and then by link URL.
+func (cfg *config) nofollowLinks() []nofollowLink {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	result := []nofollowLink{}
+	for _, edge := range cfg.links.edges {
+		if !hasRel(edge.Rel, "nofollow") {
+			continue
+		}
+		targetURL, err := url.Parse(edge.TargetURL)
+		if err != nil || !cfg.isSameSite(targetURL) {
+			continue
+		}
+		result = append(result, nofollowLink{
+			SourceURL:  cfg.pageURL(edge.Source),
+			LinkURL:    edge.TargetURL,
+			AnchorText: edge.AnchorText,
+			Rel:        edge.Rel,
+		})
+	}
+
+	slices.SortFunc(result, func(a, b nofollowLink) int {
+		return cmp.Or(cmp.Compare(a.SourceURL, b.SourceURL), cmp.Compare(a.LinkURL, b.LinkURL))
+	})
+	return result
+}
+
+// noindexedPages lists the pages asking not to be indexed, most linked first.
+// Call it after applyLinkGraph.
+func (cfg *config) noindexedPages() []PageData {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	result := []PageData{}
+	for _, page := range cfg.pages {
+		if page.NoIndex {
+			result = append(result, page)
+		}
+	}
+
+	slices.SortFunc(result, func(a, b PageData) int {
+		return cmp.Or(cmp.Compare(b.InboundLinks, a.InboundLinks), cmp.Compare(a.URL, b.URL))
+	})
+	return result
+}
+
+func writeNofollowLinksReport(links []nofollowLink, filename string) error {
+	rows := [][]string{}
+	for _, link := range links {
+		rows = append(rows, []string{
+			link.SourceURL,
+			link.LinkURL,
+			link.AnchorText,
+			link.Rel,
+		})
+	}
+	return writeCSV(filename, []string{"source_page_url", "link_url", "anchor_text", "rel"}, rows)
+}
+
+func writeNoindexedPagesReport(pages []PageData, filename string) error {
+	rows := [][]string{}
+	for _, page := range pages {
+		rows = append(rows, []string{
+			page.URL,
+			strconv.Itoa(page.InboundLinks),
+			strconv.Itoa(len(page.ReferringPages)),
+			strconv.FormatBool(page.NoFollow),
+			strconv.FormatBool(page.NoArchive),
+		})
+	}
+	return writeCSV(filename, []string{"page_url", "inbound_links", "referring_pages", "nofollow", "noarchive"}, rows)
+}
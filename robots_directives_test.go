@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// getRobotsDirectivesFromHTML parses html on its own to test robotsDirectivesFromDocument.
+func getRobotsDirectivesFromHTML(html string) (RobotsDirectives, error) {
+	reader := strings.NewReader(html)
+	doc, err := goquery.NewDocumentFromReader(reader)
+	if err != nil {
+		return RobotsDirectives{}, err
+	}
+	return robotsDirectivesFromDocument(doc), nil
+}
+
+func TestGetRobotsDirectivesFromHTML(t *testing.T) {
+	tests := []struct {
+		name      string
+		inputBody string
+		expected  RobotsDirectives
+	}{
+		{
+			name:      "no meta robots",
+			inputBody: `<html><head><title>Hi</title></head></html>`,
+			expected:  RobotsDirectives{},
+		},
+		{
+			name:      "noindex and nofollow",
+			inputBody: `<html><head><meta name="robots" content="noindex, nofollow"></head></html>`,
+			expected:  RobotsDirectives{NoIndex: true, NoFollow: true},
+		},
+		{
+			name:      "none and case",
+			inputBody: `<html><head><meta name="ROBOTS" content="None"></head></html>`,
+			expected:  RobotsDirectives{NoIndex: true, NoFollow: true},
+		},
+		{
+			name:      "our user agent",
+			inputBody: `<html><head><meta name="robots" content="noarchive"><meta name="bootcrawler" content="noindex"></head></html>`,
+			expected:  RobotsDirectives{NoIndex: true, NoArchive: true},
+		},
+		{
+			name:      "another user agent",
+			inputBody: `<html><head><meta name="googlebot" content="noindex"></head></html>`,
+			expected:  RobotsDirectives{},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := getRobotsDirectivesFromHTML(tc.inputBody)
+			if err != nil {
+				t.Errorf("Test %v - '%s' FAIL: unexpected error: %v", i+1, tc.name, err)
+				return
+			}
+			if actual != tc.expected {
+				t.Errorf("Test %v - %s\nExpected: %+v\nActual: %+v", i+1, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseXRobotsTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []string
+		expected RobotsDirectives
+	}{
+		{
+			name:     "plain directives",
+			values:   []string{"noindex, nofollow"},
+			expected: RobotsDirectives{NoIndex: true, NoFollow: true},
+		},
+		{
+			name:     "several headers",
+			values:   []string{"noarchive", "nofollow"},
+			expected: RobotsDirectives{NoFollow: true, NoArchive: true},
+		},
+		{
+			name:     "for another user agent",
+			values:   []string{"googlebot: noindex"},
+			expected: RobotsDirectives{},
+		},
+		{
+			name:     "for us",
+			values:   []string{"BootCrawler: noindex"},
+			expected: RobotsDirectives{NoIndex: true},
+		},
+		{
+			name:     "directive with a value",
+			values:   []string{"unavailable_after: 25 Jun 2030 15:00:00 PST, noarchive"},
+			expected: RobotsDirectives{NoArchive: true},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := parseXRobotsTag(tc.values)
+			if actual != tc.expected {
+				t.Errorf("Test %v - %s\nExpected: %+v\nActual: %+v", i+1, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCrawlObeysNofollow(t *testing.T) {
+	server, requested := newTestSiteWithDirectives(t)
+	cfg, err := configure(server.URL, 1, 100, -1, true, testFetcherSettings())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg.obeyNofollow = true
+	cfg.enqueue(server.URL+"/", discoveredByLink, 0)
+	cfg.crawl(context.Background())
+	cfg.applyLinkGraph()
+
+	crawled := requested()
+	slices.Sort(crawled)
+	if expected := []string{"/", "/b", "/hidden"}; !reflect.DeepEqual(crawled, expected) {
+		t.Errorf("Expected crawled: %v\nActual: %v", expected, crawled)
+	}
+
+	expectedLinks := []nofollowLink{
+		{SourceURL: server.URL + "/", LinkURL: server.URL + "/a", AnchorText: "A", Rel: "nofollow sponsored"},
+	}
+	if actual := cfg.nofollowLinks(); !reflect.DeepEqual(actual, expectedLinks) {
+		t.Errorf("Expected nofollow links: %+v\nActual: %+v", expectedLinks, actual)
+	}
+
+	noindexed := cfg.noindexedPages()
+	if len(noindexed) != 1 || noindexed[0].URL != server.URL+"/hidden" || noindexed[0].InboundLinks != 1 {
+		t.Errorf("Expected /hidden as the only noindexed page, got %+v", noindexed)
+	}
+	if page := cfg.pages[cfg.baseURL.Hostname()+"/b"]; !page.NoFollow || page.NoIndex {
+		t.Errorf("Expected /b to be nofollow only, got %+v", page.RobotsDirectives)
+	}
+}
+
+// newTestSiteWithDirectives serves pages using every kind of robots directive,
+// recording which paths are requested.
+func newTestSiteWithDirectives(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	return newRecordingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body>
+				<a href="/a" rel="nofollow sponsored">A</a>
+				<a href="/b">B</a>
+				<a href="/hidden">Hidden</a>
+				<a href="https://example.com/" rel="nofollow">Elsewhere</a>
+			</body></html>`)
+		case "/b":
+			fmt.Fprint(w, `<html><head><meta name="robots" content="nofollow"></head><body><a href="/c">C</a></body></html>`)
+		case "/hidden":
+			w.Header().Set("X-Robots-Tag", "noindex")
+			fmt.Fprint(w, `<html><body><h1>Hidden</h1></body></html>`)
+		default:
+			fmt.Fprint(w, `<html><body></body></html>`)
+		}
+	})
+}
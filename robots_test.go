@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRobotsAllowed(t *testing.T) {
+	robotsBody := `
+# example robots.txt
+User-agent: *
+Disallow: /private/
+Allow: /private/public-page
+Disallow: /*.pdf$
+Disallow: /search*q=
+
+User-agent: BootCrawler
+User-agent: OtherBot
+Disallow: /no-boots/
+Crawl-delay: 1.5
+
+Sitemap: https://example.com/sitemap.xml
+`
+	robots := parseRobotsTxt(robotsBody)
+
+	tests := []struct {
+		name      string
+		userAgent string
+		path      string
+		expected  bool
+	}{
+		{
+			name:      "no matching rule",
+			userAgent: "SomeBot/2.0",
+			path:      "/about",
+			expected:  true,
+		},
+		{
+			name:      "disallowed prefix",
+			userAgent: "SomeBot/2.0",
+			path:      "/private/data",
+			expected:  false,
+		},
+		{
+			name:      "longer allow overrides disallow",
+			userAgent: "SomeBot/2.0",
+			path:      "/private/public-page",
+			expected:  true,
+		},
+		{
+			name:      "end anchor matches",
+			userAgent: "SomeBot/2.0",
+			path:      "/files/report.pdf",
+			expected:  false,
+		},
+		{
+			name:      "end anchor doesn't match longer path",
+			userAgent: "SomeBot/2.0",
+			path:      "/files/report.pdf?download=1",
+			expected:  true,
+		},
+		{
+			name:      "wildcard in the middle",
+			userAgent: "SomeBot/2.0",
+			path:      "/search/results?q=go",
+			expected:  false,
+		},
+		{
+			name:      "specific group replaces wildcard group",
+			userAgent: "BootCrawler/1.0",
+			path:      "/private/data",
+			expected:  true,
+		},
+		{
+			name:      "specific group rules apply",
+			userAgent: "BootCrawler/1.0",
+			path:      "/no-boots/page",
+			expected:  false,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := robots.groupFor(tc.userAgent).allowed(tc.path)
+			if actual != tc.expected {
+				t.Errorf("Test %v - %s\nExpected: %v\nActual: %v", i+1, tc.name, tc.expected, actual)
+			}
+		})
+	}
+
+	if delay := robots.groupFor("BootCrawler/1.0").crawlDelay; delay != 1500*time.Millisecond {
+		t.Errorf("Expected crawl delay of 1.5s, got %v", delay)
+	}
+	if delay := robots.groupFor("SomeBot/2.0").crawlDelay; delay != 0 {
+		t.Errorf("Expected no crawl delay for wildcard group, got %v", delay)
+	}
+	// Only an exact product token selects a group
+	for _, agent := range []string{"", "boot", "Crawler", "BootCrawler"} {
+		body := "User-agent: " + agent + "\nDisallow: /\n\nUser-agent: *\nDisallow: /private/\n"
+		group := parseRobotsTxt(body).groupFor("BootCrawler/1.0")
+		expected := agent == "BootCrawler"
+		if actual := !group.allowed("/about"); actual != expected {
+			t.Errorf("User-agent %q: expected its group to apply: %v, got %v", agent, expected, actual)
+		}
+	}
+
+	if !reflect.DeepEqual(robots.sitemaps, []string{"https://example.com/sitemap.xml"}) {
+		t.Errorf("Unexpected sitemaps: %v", robots.sitemaps)
+	}
+}
+
+func TestCrawlRobots(t *testing.T) {
+	tests := []struct {
+		name              string
+		robotsStatus      int
+		ignoreRobots      bool
+		expectedRequested []string
+		expectedBlocked   []string
+		expectedMinDelay  time.Duration
+	}{
+		{
+			name:              "disallowed pages are skipped without fetching",
+			robotsStatus:      http.StatusOK,
+			expectedRequested: []string{"/robots.txt", "/", "/public"},
+			expectedBlocked:   []string{"/private/page"},
+			expectedMinDelay:  50 * time.Millisecond,
+		},
+		{
+			name:              "missing robots.txt allows everything",
+			robotsStatus:      http.StatusNotFound,
+			expectedRequested: []string{"/robots.txt", "/", "/private/page", "/public"},
+			expectedBlocked:   []string{},
+		},
+		{
+			name:              "server error disallows everything",
+			robotsStatus:      http.StatusInternalServerError,
+			expectedRequested: []string{"/robots.txt"},
+			expectedBlocked:   []string{"/"},
+		},
+		{
+			name:              "ignore robots bypasses the check",
+			robotsStatus:      http.StatusOK,
+			ignoreRobots:      true,
+			expectedRequested: []string{"/", "/private/page", "/public"},
+			expectedBlocked:   []string{},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requested := []string{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requested = append(requested, r.URL.Path)
+				mu.Unlock()
+
+				if r.URL.Path == "/robots.txt" {
+					w.WriteHeader(tc.robotsStatus)
+					fmt.Fprint(w, "User-agent: *\nDisallow: /private/\nCrawl-delay: 0.05\n")
+					return
+				}
+				w.Header().Set("Content-Type", "text/html")
+				fmt.Fprint(w, `<html><body><a href="/private/page">private</a><a href="/public">public</a></body></html>`)
+			}))
+			defer server.Close()
+
+			settings := testFetcherSettings()
+			settings.maxRetries = 0
+			cfg, err := configure(server.URL, 1, 100, -1, tc.ignoreRobots, settings)
+			if err != nil {
+				t.Fatalf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+			}
+			cfg.enqueue(server.URL+"/", discoveredByLink, 0)
+			cfg.crawl(context.Background())
+
+			mu.Lock()
+			actualRequested := slices.Clone(requested)
+			mu.Unlock()
+			if !reflect.DeepEqual(tc.expectedRequested, actualRequested) {
+				t.Errorf("Test %v - %s\nExpected requests: %v\nActual: %v", i+1, tc.name, tc.expectedRequested, actualRequested)
+			}
+
+			blocked := []string{}
+			for _, page := range cfg.pages {
+				if page.SkipReason == robotsBlockedReason {
+					blocked = append(blocked, page.URL[len(server.URL):])
+				}
+			}
+			slices.Sort(blocked)
+			if !reflect.DeepEqual(tc.expectedBlocked, blocked) {
+				t.Errorf("Test %v - %s\nExpected blocked: %v\nActual: %v", i+1, tc.name, tc.expectedBlocked, blocked)
+			}
+
+			minDelay := time.Duration(0)
+			cfg.fetcher.limiter.mu.Lock()
+			if state, ok := cfg.fetcher.limiter.hosts[cfg.baseURL.Host]; ok {
+				minDelay = state.minInterval
+			}
+			cfg.fetcher.limiter.mu.Unlock()
+			if minDelay != tc.expectedMinDelay {
+				t.Errorf("Test %v - %s\nExpected a min delay of %v from Crawl-delay, got %v", i+1, tc.name, tc.expectedMinDelay, minDelay)
+			}
+		})
+	}
+}
+
+func TestRobotsInterrupted(t *testing.T) {
+	var mu sync.Mutex
+	robotsRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			mu.Lock()
+			robotsRequests++
+			first := robotsRequests == 1
+			mu.Unlock()
+			if first {
+				// Hang until the crawler gives up on the request
+				<-r.Context().Done()
+				return
+			}
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body></body></html>")
+	}))
+	defer server.Close()
+
+	settings := testFetcherSettings()
+	settings.maxRetries = 0
+	cfg, err := configure(server.URL, 1, 100, -1, false, settings)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	item := frontierItem{rawURL: server.URL + "/page", normalizedURL: cfg.baseURL.Hostname() + "/page", depth: 1}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	cfg.crawlPage(ctx, item)
+
+	// The page goes back to the frontier instead of being marked as blocked
+	if page, ok := cfg.pages[item.normalizedURL]; ok {
+		t.Errorf("Expected no page data for an interrupted robots.txt check, got %+v", page)
+	}
+	if len(cfg.frontier) != 1 || cfg.frontier[0] != item {
+		t.Errorf("Expected the page to be requeued, frontier is %+v", cfg.frontier)
+	}
+
+	// The canceled fetch isn't cached, so robots.txt is fetched again
+	pageURL, _ := url.Parse(item.rawURL)
+	allowed, err := cfg.robots.allowed(context.Background(), pageURL)
+	if err != nil || !allowed {
+		t.Errorf("Expected /page to be allowed after robots.txt was fetched, got %v, %v", allowed, err)
+	}
+	privateURL, _ := url.Parse(server.URL + "/private/page")
+	if allowed, _ := cfg.robots.allowed(context.Background(), privateURL); allowed {
+		t.Errorf("Expected /private/page to be disallowed")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if robotsRequests != 2 {
+		t.Errorf("Expected robots.txt to be requested twice, got %d", robotsRequests)
+	}
+}
+
+func TestRobotsUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serverURL := server.URL
+	server.Close()
+
+	settings := testFetcherSettings()
+	settings.maxRetries = 0
+	cfg, err := configure(serverURL, 1, 100, -1, false, settings)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg.enqueue(serverURL+"/", discoveredByLink, 0)
+	cfg.crawl(context.Background())
+
+	if len(cfg.pages) != 1 {
+		t.Fatalf("Expected 1 page, got %+v", cfg.pages)
+	}
+	for _, page := range cfg.pages {
+		// The host is down, which says nothing about what robots.txt allows
+		if page.SkipReason != robotsUnreachableReason {
+			t.Errorf("Expected skip reason %q, got %q", robotsUnreachableReason, page.SkipReason)
+		}
+		if page.ErrorCategory != errorCategoryConnectionRefused || page.FetchError == "" {
+			t.Errorf("Expected a connection refused fetch error, got %q (%q)", page.ErrorCategory, page.FetchError)
+		}
+		if !isBroken(page) {
+			t.Errorf("Expected the page to count as broken")
+		}
+	}
+}
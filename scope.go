@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Scope modes: which hosts belong to the crawled site.
+const (
+	// scopeHost crawls only the hosts of the seed URLs.
+	scopeHost = "host"
+	// scopeDomain crawls every subdomain of the seed URLs' registrable domains,
+	// so www.example.com, blog.example.com and example.com are one site.
+	scopeDomain = "domain"
+	// scopeAllowlist crawls the hosts of the seed URLs and the listed hosts.
+	scopeAllowlist = "allowlist"
+)
+
+type crawlScope struct {
+	mode  string
+	hosts []string
+}
+
+// parseSeedURL parses a URL to start crawling from. It must be an absolute
+// http(s) URL, since a seed's host becomes part of the scope.
+func parseSeedURL(rawURL string) (*url.URL, error) {
+	seed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if (seed.Scheme != "http" && seed.Scheme != "https") || seed.Hostname() == "" {
+		return nil, fmt.Errorf("%q isn't an absolute http or https URL", rawURL)
+	}
+	return seed, nil
+}
+
+func newCrawlScope(mode string, seeds []*url.URL, allowedHosts []string) (crawlScope, error) {
+	scope := crawlScope{mode: mode}
+	switch mode {
+	case scopeHost, scopeDomain:
+		if len(allowedHosts) > 0 {
+			return crawlScope{}, fmt.Errorf("allowed hosts need the %s scope", scopeAllowlist)
+		}
+	case scopeAllowlist:
+		for _, host := range allowedHosts {
+			scope.hosts = append(scope.hosts, strings.ToLower(host))
+		}
+	default:
+		return crawlScope{}, fmt.Errorf("unknown scope %q, want %s, %s or %s", mode, scopeHost, scopeDomain, scopeAllowlist)
+	}
+	for _, seed := range seeds {
+		// An empty host would put every mailto: and javascript: link in scope
+		if seed.Hostname() == "" {
+			return crawlScope{}, fmt.Errorf("seed URL %q has no host", seed)
+		}
+		scope.hosts = append(scope.hosts, seed.Hostname())
+	}
+	return scope, nil
+}
+
+// contains reports whether a host name is in scope. Hosts are compared after
+// normalization, so www and bare hosts match when the rules merge them.
+func (s crawlScope) contains(hostname string, rules normalizeRules) bool {
+	hostname = s.key(hostname, rules)
+	for _, host := range s.hosts {
+		if s.key(host, rules) == hostname {
+			return true
+		}
+	}
+	return false
+}
+
+func (s crawlScope) key(hostname string, rules normalizeRules) string {
+	hostname = rules.normalizeHost(hostname)
+	if s.mode == scopeDomain {
+		return registrableDomain(hostname)
+	}
+	return hostname
+}
+
+// registrableDomain returns the part of a host name its owner registered, such
+// as example.co.uk for www.example.co.uk. Hosts without one, like IP addresses
+// and localhost, are returned as they are.
+func registrableDomain(hostname string) string {
+	if net.ParseIP(hostname) != nil {
+		return hostname
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(hostname)
+	if err != nil {
+		return hostname
+	}
+	return domain
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCrawlScope(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          string
+		seeds         []string
+		allowedHosts  []string
+		mergeWWW      bool
+		hostname      string
+		expected      bool
+		errorContains string
+	}{
+		{
+			name:     "exact host",
+			mode:     scopeHost,
+			seeds:    []string{"https://www.example.com/"},
+			hostname: "www.example.com",
+			expected: true,
+		},
+		{
+			name:     "subdomain outside host scope",
+			mode:     scopeHost,
+			seeds:    []string{"https://www.example.com/"},
+			hostname: "blog.example.com",
+			expected: false,
+		},
+		{
+			name:     "bare host with www merged",
+			mode:     scopeHost,
+			seeds:    []string{"https://www.example.com/"},
+			mergeWWW: true,
+			hostname: "example.com",
+			expected: true,
+		},
+		{
+			name:     "every seed host is in scope",
+			mode:     scopeHost,
+			seeds:    []string{"https://www.example.com/", "https://shop.example.net/"},
+			hostname: "SHOP.example.net",
+			expected: true,
+		},
+		{
+			name:     "subdomain in domain scope",
+			mode:     scopeDomain,
+			seeds:    []string{"https://www.example.com/"},
+			hostname: "blog.example.com",
+			expected: true,
+		},
+		{
+			name:     "registrable domain under a multi-label suffix",
+			mode:     scopeDomain,
+			seeds:    []string{"https://www.example.co.uk/"},
+			hostname: "shop.example.co.uk",
+			expected: true,
+		},
+		{
+			name:     "other domain under the same suffix",
+			mode:     scopeDomain,
+			seeds:    []string{"https://www.example.co.uk/"},
+			hostname: "www.other.co.uk",
+			expected: false,
+		},
+		{
+			name:     "IP address in domain scope",
+			mode:     scopeDomain,
+			seeds:    []string{"http://127.0.0.1:8080/"},
+			hostname: "10.0.0.1",
+			expected: false,
+		},
+		{
+			name:         "allowlisted host",
+			mode:         scopeAllowlist,
+			seeds:        []string{"https://www.example.com/"},
+			allowedHosts: []string{"Docs.Example.org"},
+			hostname:     "docs.example.org",
+			expected:     true,
+		},
+		{
+			name:         "host not on the allowlist",
+			mode:         scopeAllowlist,
+			seeds:        []string{"https://www.example.com/"},
+			allowedHosts: []string{"docs.example.org"},
+			hostname:     "blog.example.com",
+			expected:     false,
+		},
+		{
+			name:          "allowed hosts without allowlist scope",
+			mode:          scopeDomain,
+			seeds:         []string{"https://www.example.com/"},
+			allowedHosts:  []string{"docs.example.org"},
+			errorContains: "need the allowlist scope",
+		},
+		{
+			name:          "unknown mode",
+			mode:          "everything",
+			seeds:         []string{"https://www.example.com/"},
+			errorContains: "unknown scope",
+		},
+		{
+			name:          "seed without a host",
+			mode:          scopeHost,
+			seeds:         []string{"https://www.example.com/", "example.com/blog"},
+			errorContains: "has no host",
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			seeds := []*url.URL{}
+			for _, rawSeed := range tc.seeds {
+				seed, err := url.Parse(rawSeed)
+				if err != nil {
+					t.Fatal(err)
+				}
+				seeds = append(seeds, seed)
+			}
+			scope, err := newCrawlScope(tc.mode, seeds, tc.allowedHosts)
+			if err == nil && tc.errorContains != "" {
+				t.Fatalf("Test %v - %s\nExpected error containing %q", i+1, tc.name, tc.errorContains)
+			}
+			if err != nil && tc.errorContains != "" {
+				if !strings.Contains(err.Error(), tc.errorContains) {
+					t.Errorf("Test %v - %s\nExpected error containing %q, got %v", i+1, tc.name, tc.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+			}
+
+			rules := defaultNormalizeRules()
+			rules.mergeWWW = tc.mergeWWW
+			actual := scope.contains(tc.hostname, rules)
+			if actual != tc.expected {
+				t.Errorf("Test %v - %s\nExpected: %v\nActual: %v", i+1, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseSeedURL(t *testing.T) {
+	tests := []struct {
+		rawURL  string
+		wantErr bool
+	}{
+		{rawURL: "https://example.com/blog"},
+		{rawURL: "http://127.0.0.1:8080/"},
+		{rawURL: "example.com/blog", wantErr: true},
+		{rawURL: "//example.com/blog", wantErr: true},
+		{rawURL: "mailto:someone@example.com", wantErr: true},
+		{rawURL: "ftp://example.com/", wantErr: true},
+		{rawURL: "https:///path", wantErr: true},
+	}
+
+	for i, tc := range tests {
+		_, err := parseSeedURL(tc.rawURL)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("Test %v - %s\nExpected error: %v\nActual: %v", i+1, tc.rawURL, tc.wantErr, err)
+		}
+		// The base URL is held to the same rules as any other seed
+		_, err = configure(tc.rawURL, 1, 10, -1, true, testFetcherSettings())
+		if (err != nil) != tc.wantErr {
+			t.Errorf("Test %v - %s\nExpected configure error: %v\nActual: %v", i+1, tc.rawURL, tc.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	goquery "github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// What a selector rule takes from the elements it matches.
+const (
+	takeText = "text"
+	takeHTML = "html"
+	takeAttr = "attr"
+)
+
+// selectorValueSeparator joins the values of a rule that takes every match.
+const selectorValueSeparator = " | "
+
+// selectorRule is one user-defined field, read from a rules file like:
+//
+//	[
+//	  {"field": "price", "selector": ".price"},
+//	  {"field": "author", "selector": "meta[name=author]", "take": "attr", "attr": "content"},
+//	  {"field": "tags", "selector": ".tags a", "all": true}
+//	]
+type selectorRule struct {
+	Field    string `json:"field"`
+	Selector string `json:"selector"`
+	// Take is text (the default), html for the inner HTML, or attr for the attribute named by Attr.
+	Take string `json:"take"`
+	Attr string `json:"attr"`
+	// All takes every match instead of only the first one.
+	All bool `json:"all"`
+
+	matcher cascadia.Selector
+}
+
+// loadSelectorRules reads and checks a rules file. Field names become CSV
+// columns, so they must be unique and not clash with the built-in columns.
+func loadSelectorRules(filename string) ([]selectorRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	rules := []selectorRule{}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("couldn't decode selector rules: %v", err)
+	}
+
+	fields := make(map[string]bool)
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Field == "" {
+			return nil, fmt.Errorf("selector rule %d has no field name", i+1)
+		}
+		if slices.Contains(csvReportColumns, rule.Field) {
+			return nil, fmt.Errorf("selector rule field %q clashes with a built-in report column", rule.Field)
+		}
+		if fields[rule.Field] {
+			return nil, fmt.Errorf("selector rule field %q is used more than once", rule.Field)
+		}
+		fields[rule.Field] = true
+
+		rule.matcher, err = cascadia.Compile(rule.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("selector rule %q: invalid selector %q: %v", rule.Field, rule.Selector, err)
+		}
+		switch rule.Take {
+		case "":
+			rule.Take = takeText
+		case takeText, takeHTML:
+		case takeAttr:
+			if rule.Attr == "" {
+				return nil, fmt.Errorf("selector rule %q takes an attribute but doesn't name one", rule.Field)
+			}
+		default:
+			return nil, fmt.Errorf("selector rule %q: unknown take %q, want text, html or attr", rule.Field, rule.Take)
+		}
+	}
+	return rules, nil
+}
+
+// selectorRuleFields returns the field names of rules, in order.
+func selectorRuleFields(rules []selectorRule) []string {
+	fields := []string{}
+	for _, rule := range rules {
+		fields = append(fields, rule.Field)
+	}
+	return fields
+}
+
+// selectorRulesExtractor fills PageData.CustomFields from the rules.
+func selectorRulesExtractor(rules []selectorRule) Extractor {
+	return ExtractorFunc(func(page *ParsedPage, data *PageData) error {
+		data.CustomFields = make(map[string]string, len(rules))
+		for _, rule := range rules {
+			data.CustomFields[rule.Field] = rule.extract(page.Doc)
+		}
+		return nil
+	})
+}
+
+func (r selectorRule) extract(doc *goquery.Document) string {
+	matches := doc.FindMatcher(r.matcher)
+	values := []string{}
+	matches.EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		value := ""
+		switch r.Take {
+		case takeHTML:
+			html, err := s.Html()
+			if err != nil {
+				return true
+			}
+			value = strings.TrimSpace(html)
+		case takeAttr:
+			attr, exists := s.Attr(r.Attr)
+			if !exists {
+				return true
+			}
+			value = strings.TrimSpace(attr)
+		default:
+			value = collapseWhitespace(s.Text())
+		}
+		values = append(values, value)
+		return r.All
+	})
+	return strings.Join(values, selectorValueSeparator)
+}
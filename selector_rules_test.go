@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectorRules(t *testing.T) {
+	html := `<html><head><meta name="author" content=" Ada Lovelace "></head><body>
+		<nav class="breadcrumb"><a href="/">Home</a> <a href="/shoes">Shoes</a></nav>
+		<span class="price">
+			€ 59,90
+		</span>
+		<div class="description"><p>Soft <b>leather</b></p></div>
+		<ul class="tags"><li>red</li><li>sale</li></ul>
+	</body></html>`
+	rulesJSON := `[
+		{"field": "price", "selector": ".price"},
+		{"field": "author", "selector": "meta[name=author]", "take": "attr", "attr": "content"},
+		{"field": "description", "selector": ".description", "take": "html"},
+		{"field": "tags", "selector": ".tags li", "all": true},
+		{"field": "first_tag", "selector": ".tags li"},
+		{"field": "breadcrumb", "selector": "nav.breadcrumb a", "take": "attr", "attr": "href", "all": true},
+		{"field": "sku", "selector": ".sku"}
+	]`
+	filename := filepath.Join(t.TempDir(), "selectors.json")
+	if err := os.WriteFile(filename, []byte(rulesJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := loadSelectorRules(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	page, err := parsePage(html, "https://example.com/shoes/red")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data := page.extract([]Extractor{selectorRulesExtractor(rules)})
+
+	expected := map[string]string{
+		"price":       "€ 59,90",
+		"author":      "Ada Lovelace",
+		"description": "<p>Soft <b>leather</b></p>",
+		"tags":        "red | sale",
+		"first_tag":   "red",
+		"breadcrumb":  "/ | /shoes",
+		"sku":         "",
+	}
+	if !reflect.DeepEqual(data.CustomFields, expected) {
+		t.Errorf("Expected: %v\nActual: %v", expected, data.CustomFields)
+	}
+	if fields := selectorRuleFields(rules); !reflect.DeepEqual(fields, []string{"price", "author", "description", "tags", "first_tag", "breadcrumb", "sku"}) {
+		t.Errorf("Expected fields in rule order, got %v", fields)
+	}
+}
+
+func TestLoadSelectorRulesErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		rulesJSON     string
+		errorContains string
+	}{
+		{
+			name:          "invalid JSON",
+			rulesJSON:     `{"field": "price"}`,
+			errorContains: "couldn't decode",
+		},
+		{
+			name:          "missing field name",
+			rulesJSON:     `[{"selector": ".price"}]`,
+			errorContains: "has no field name",
+		},
+		{
+			name:          "duplicate field",
+			rulesJSON:     `[{"field": "price", "selector": ".a"}, {"field": "price", "selector": ".b"}]`,
+			errorContains: "used more than once",
+		},
+		{
+			name:          "built-in column",
+			rulesJSON:     `[{"field": "h1", "selector": "h1"}]`,
+			errorContains: "built-in report column",
+		},
+		{
+			name:          "invalid selector",
+			rulesJSON:     `[{"field": "price", "selector": "div[["}]`,
+			errorContains: "invalid selector",
+		},
+		{
+			name:          "attr without a name",
+			rulesJSON:     `[{"field": "author", "selector": "meta", "take": "attr"}]`,
+			errorContains: "doesn't name one",
+		},
+		{
+			name:          "unknown take",
+			rulesJSON:     `[{"field": "price", "selector": ".price", "take": "value"}]`,
+			errorContains: "unknown take",
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "selectors.json")
+			if err := os.WriteFile(filename, []byte(tc.rulesJSON), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadSelectorRules(filename)
+			if err == nil || !strings.Contains(err.Error(), tc.errorContains) {
+				t.Errorf("Test %v - %s\nExpected error containing %q, got %v", i+1, tc.name, tc.errorContains, err)
+			}
+		})
+	}
+}
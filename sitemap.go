@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Limits that keep a misbehaving sitemap index from running away with the crawl.
+const (
+	maxSitemapDepth = 3
+	maxSitemaps     = 1000
+	maxSitemapBytes = 50 * 1024 * 1024
+)
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// sitemapDocument covers both <urlset> and <sitemapindex> documents.
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+// parseSitemap returns the page URLs and the nested sitemap URLs listed in a
+// sitemap document. Gzip-compressed documents are decompressed first.
+func parseSitemap(body []byte) (pageURLs, sitemapURLs []string, err error) {
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, fmt.Errorf("couldn't decompress sitemap: %v", err)
+		}
+		defer reader.Close()
+		body, err = io.ReadAll(io.LimitReader(reader, maxSitemapBytes))
+		if err != nil {
+			return nil, nil, fmt.Errorf("couldn't decompress sitemap: %v", err)
+		}
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, nil, fmt.Errorf("couldn't parse sitemap: %v", err)
+	}
+
+	switch doc.XMLName.Local {
+	case "urlset":
+		for _, u := range doc.URLs {
+			if loc := strings.TrimSpace(u.Loc); loc != "" {
+				pageURLs = append(pageURLs, loc)
+			}
+		}
+	case "sitemapindex":
+		for _, s := range doc.Sitemaps {
+			if loc := strings.TrimSpace(s.Loc); loc != "" {
+				sitemapURLs = append(sitemapURLs, loc)
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf("unexpected sitemap root element <%s>", doc.XMLName.Local)
+	}
+	return pageURLs, sitemapURLs, nil
+}
+
+// collectSitemapURLs finds the sitemaps of every seed host through robots.txt and
+// /sitemap.xml and returns every page URL they list.
+func (cfg *config) collectSitemapURLs(ctx context.Context) []string {
+	queue := []string{}
+	for _, seed := range cfg.seeds {
+		if !cfg.ignoreRobots {
+			if robots, err := cfg.robots.get(ctx, seed); err == nil {
+				queue = append(queue, robots.sitemaps...)
+			}
+		}
+		queue = append(queue, seed.Scheme+"://"+seed.Host+"/sitemap.xml")
+	}
+
+	seen := make(map[string]bool)
+	depth := make(map[string]int)
+	pageURLs := []string{}
+	for len(queue) > 0 && len(seen) < maxSitemaps && ctx.Err() == nil {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if seen[sitemapURL] {
+			continue
+		}
+		seen[sitemapURL] = true
+
+		body, err := cfg.getSitemap(ctx, sitemapURL)
+		if err != nil {
+			fmt.Printf("Error - getSitemap: %v\n", err)
+			continue
+		}
+		pages, nested, err := parseSitemap(body)
+		if err != nil {
+			fmt.Printf("Error - parseSitemap %s: %v\n", sitemapURL, err)
+			continue
+		}
+		pageURLs = append(pageURLs, pages...)
+		if depth[sitemapURL] >= maxSitemapDepth {
+			continue
+		}
+		for _, n := range nested {
+			if _, ok := depth[n]; !ok {
+				depth[n] = depth[sitemapURL] + 1
+			}
+			queue = append(queue, n)
+		}
+	}
+
+	return pageURLs
+}
+
+// getSitemap fetches a sitemap, which may be larger than the max body size for
+// pages, up to maxSitemapBytes.
+func (cfg *config) getSitemap(ctx context.Context, rawURL string) ([]byte, error) {
+	result, err := cfg.fetcher.withMaxBodyBytes(maxSitemapBytes).getWithRetry(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if result.res.StatusCode >= 400 {
+		return nil, fmt.Errorf("error (%d) getting %s", result.res.StatusCode, rawURL)
+	}
+	if result.truncated {
+		return nil, fmt.Errorf("sitemap %s is larger than %d bytes", rawURL, maxSitemapBytes)
+	}
+	return result.body, nil
+}
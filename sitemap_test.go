@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseSitemap(t *testing.T) {
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>https://example.com/</loc><lastmod>2024-01-01</lastmod></url>
+	<url><loc>
+		https://example.com/orphan
+	</loc></url>
+</urlset>`
+	index := `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>https://example.com/sitemap-posts.xml.gz</loc></sitemap>
+	<sitemap><loc>https://example.com/sitemap-pages.xml</loc></sitemap>
+</sitemapindex>`
+
+	tests := []struct {
+		name             string
+		body             []byte
+		expectedPages    []string
+		expectedSitemaps []string
+		errorContains    string
+	}{
+		{
+			name:          "urlset",
+			body:          []byte(urlset),
+			expectedPages: []string{"https://example.com/", "https://example.com/orphan"},
+		},
+		{
+			name:             "sitemap index",
+			body:             []byte(index),
+			expectedSitemaps: []string{"https://example.com/sitemap-posts.xml.gz", "https://example.com/sitemap-pages.xml"},
+		},
+		{
+			name:          "gzip compressed urlset",
+			body:          gzipBytes(t, urlset),
+			expectedPages: []string{"https://example.com/", "https://example.com/orphan"},
+		},
+		{
+			name:          "not a sitemap",
+			body:          []byte("<html><body>Not found</body></html>"),
+			errorContains: "unexpected sitemap root element",
+		},
+		{
+			name:          "invalid xml",
+			body:          []byte("User-agent: *"),
+			errorContains: "couldn't parse sitemap",
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pages, sitemaps, err := parseSitemap(tc.body)
+			if tc.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errorContains) {
+					t.Errorf("Test %v - %s\nExpected error containing '%s', got: %v", i+1, tc.name, tc.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+				return
+			}
+			if !reflect.DeepEqual(pages, tc.expectedPages) {
+				t.Errorf("Test %v - %s\nExpected pages: %v\nActual: %v", i+1, tc.name, tc.expectedPages, pages)
+			}
+			if !reflect.DeepEqual(sitemaps, tc.expectedSitemaps) {
+				t.Errorf("Test %v - %s\nExpected sitemaps: %v\nActual: %v", i+1, tc.name, tc.expectedSitemaps, sitemaps)
+			}
+		})
+	}
+}
+
+func TestCollectSitemapURLs(t *testing.T) {
+	index := func(paths ...string) string {
+		var b strings.Builder
+		b.WriteString(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+		for _, path := range paths {
+			fmt.Fprintf(&b, "<sitemap><loc>{{server}}%s</loc></sitemap>", path)
+		}
+		b.WriteString("</sitemapindex>")
+		return b.String()
+	}
+	urlset := func(paths ...string) string {
+		var b strings.Builder
+		b.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+		for _, path := range paths {
+			fmt.Fprintf(&b, "<url><loc>{{server}}%s</loc></url>", path)
+		}
+		b.WriteString("</urlset>")
+		return b.String()
+	}
+	largePath := "/large" + strings.Repeat("x", 2048)
+	documents := map[string]string{
+		"/robots.txt":  "User-agent: *\nDisallow:\nSitemap: {{server}}/index.xml\n",
+		"/index.xml":   index("/level1.xml", "/broken.xml", "/large.xml"),
+		"/level1.xml":  index("/pages.xml", "/level2.xml"),
+		"/level2.xml":  index("/level3.xml"),
+		"/level3.xml":  index("/level4.xml"),
+		"/level4.xml":  urlset("/too-deep"),
+		"/pages.xml":   urlset("/from-index"),
+		"/large.xml":   urlset(largePath),
+		"/sitemap.xml": urlset("/fallback"),
+	}
+
+	tests := []struct {
+		name              string
+		ignoreRobots      bool
+		expectedURLs      []string
+		expectedRequested []string
+	}{
+		{
+			name:              "robots.txt sitemaps and the /sitemap.xml fallback",
+			expectedURLs:      []string{"/fallback", "/from-index", largePath},
+			expectedRequested: []string{"/broken.xml", "/index.xml", "/large.xml", "/level1.xml", "/level2.xml", "/level3.xml", "/pages.xml", "/robots.txt", "/sitemap.xml"},
+		},
+		{
+			name:              "only the fallback when ignoring robots.txt",
+			ignoreRobots:      true,
+			expectedURLs:      []string{"/fallback"},
+			expectedRequested: []string{"/sitemap.xml"},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requested := []string{}
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requested = append(requested, r.URL.Path)
+				mu.Unlock()
+
+				document, ok := documents[r.URL.Path]
+				if !ok {
+					http.Error(w, "server error", http.StatusInternalServerError)
+					return
+				}
+				fmt.Fprint(w, strings.ReplaceAll(document, "{{server}}", server.URL))
+			}))
+			defer server.Close()
+
+			settings := testFetcherSettings()
+			settings.maxRetries = 0
+			// Sitemaps aren't held to the max body size for pages
+			settings.maxBodyBytes = 1024
+			cfg, err := configure(server.URL, 1, 100, -1, tc.ignoreRobots, settings)
+			if err != nil {
+				t.Fatalf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+			}
+
+			actual := []string{}
+			for _, pageURL := range cfg.collectSitemapURLs(context.Background()) {
+				actual = append(actual, strings.TrimPrefix(pageURL, server.URL))
+			}
+			slices.Sort(actual)
+			if !reflect.DeepEqual(tc.expectedURLs, actual) {
+				t.Errorf("Test %v - %s\nExpected: %v\nActual: %v", i+1, tc.name, tc.expectedURLs, actual)
+			}
+
+			mu.Lock()
+			actualRequested := slices.Clone(requested)
+			mu.Unlock()
+			slices.Sort(actualRequested)
+			if !reflect.DeepEqual(tc.expectedRequested, actualRequested) {
+				t.Errorf("Test %v - %s\nExpected requests: %v\nActual: %v", i+1, tc.name, tc.expectedRequested, actualRequested)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	goquery "github.com/PuerkitoBio/goquery"
+)
+
+// StructuredData is the markup a page has for social previews and search
+// engines: OpenGraph and Twitter Card meta tags, and JSON-LD.
+type StructuredData struct {
+	// OpenGraph and TwitterCard map tag names such as "og:title" or
+	// "twitter:card" to their value. A repeated tag keeps its first value.
+	OpenGraph   map[string]string
+	TwitterCard map[string]string
+	// JSONLD holds the top-level nodes of every JSON-LD block that parsed,
+	// with the nodes of @graph arrays listed on their own.
+	JSONLD []map[string]any
+	// SchemaTypes are the distinct @type values of the JSONLD nodes, sorted.
+	SchemaTypes []string
+	// JSONLDErrors describe the JSON-LD blocks that couldn't be parsed.
+	JSONLDErrors []jsonLDError
+}
+
+// jsonLDError is a <script type="application/ld+json"> that isn't valid
+// JSON-LD. Block counts the page's JSON-LD scripts from 1.
+type jsonLDError struct {
+	Block int
+	Error string
+}
+
+func extractStructuredData(page *ParsedPage, data *PageData) error {
+	data.StructuredData = structuredDataFromDocument(page.Doc)
+	return nil
+}
+
+// newStructuredData returns StructuredData for a page without any.
+func newStructuredData() StructuredData {
+	return StructuredData{
+		OpenGraph:    make(map[string]string),
+		TwitterCard:  make(map[string]string),
+		JSONLD:       []map[string]any{},
+		SchemaTypes:  []string{},
+		JSONLDErrors: []jsonLDError{},
+	}
+}
+
+func structuredDataFromDocument(doc *goquery.Document) StructuredData {
+	structured := newStructuredData()
+
+	// OpenGraph uses property= and Twitter uses name=, but both are common for either
+	doc.Find("meta[content]").Each(func(_ int, s *goquery.Selection) {
+		name := s.AttrOr("property", "")
+		if name == "" {
+			name = s.AttrOr("name", "")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		switch {
+		case strings.HasPrefix(name, "og:"):
+			if _, ok := structured.OpenGraph[name]; !ok {
+				structured.OpenGraph[name] = content
+			}
+		case strings.HasPrefix(name, "twitter:"):
+			if _, ok := structured.TwitterCard[name]; !ok {
+				structured.TwitterCard[name] = content
+			}
+		}
+	})
+
+	block := 0
+	doc.Find("script[type]").Each(func(_ int, s *goquery.Selection) {
+		scriptType, _ := s.Attr("type")
+		if !strings.EqualFold(strings.TrimSpace(scriptType), "application/ld+json") {
+			return
+		}
+		block++
+		nodes, err := parseJSONLD(s.Text())
+		if err != nil {
+			structured.JSONLDErrors = append(structured.JSONLDErrors, jsonLDError{Block: block, Error: err.Error()})
+			return
+		}
+		structured.JSONLD = append(structured.JSONLD, nodes...)
+	})
+
+	for _, node := range structured.JSONLD {
+		for _, schemaType := range jsonLDTypes(node) {
+			if !slices.Contains(structured.SchemaTypes, schemaType) {
+				structured.SchemaTypes = append(structured.SchemaTypes, schemaType)
+			}
+		}
+	}
+	slices.Sort(structured.SchemaTypes)
+	return structured
+}
+
+// parseJSONLD returns the nodes of one JSON-LD block, which may be a single
+// node, an array of nodes or a node with an @graph array.
+func parseJSONLD(text string) ([]map[string]any, error) {
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, err
+	}
+
+	var items []any
+	switch value := value.(type) {
+	case map[string]any:
+		items = []any{value}
+	case []any:
+		items = value
+	default:
+		return nil, fmt.Errorf("expected a JSON object or array, got %T", value)
+	}
+
+	nodes := []map[string]any{}
+	for _, item := range items {
+		node, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		graph, hasGraph := node["@graph"].([]any)
+		if !hasGraph {
+			nodes = append(nodes, node)
+			continue
+		}
+		for _, graphItem := range graph {
+			if graphNode, ok := graphItem.(map[string]any); ok {
+				nodes = append(nodes, graphNode)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// jsonLDTypes returns a node's @type, which may be a string or an array of them.
+func jsonLDTypes(node map[string]any) []string {
+	types := []string{}
+	switch value := node["@type"].(type) {
+	case string:
+		types = append(types, value)
+	case []any:
+		for _, item := range value {
+			if schemaType, ok := item.(string); ok {
+				types = append(types, schemaType)
+			}
+		}
+	}
+	return types
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	filenameSchemaTypesCSV  = "schema_types.csv"
+	filenameJSONLDErrorsCSV = "jsonld_errors.csv"
+)
+
+// schemaTypeUsage is a schema.org type and the pages using it in JSON-LD.
+type schemaTypeUsage struct {
+	SchemaType string
+	PageURLs   []string
+}
+
+// pageJSONLDError is a JSON-LD block of a crawled page that couldn't be parsed.
+type pageJSONLDError struct {
+	PageURL string
+	Block   int
+	Error   string
+}
+
+// schemaTypes lists every schema type found on the crawled pages, the most
+// used first.
+func (cfg *config) schemaTypes() []schemaTypeUsage {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	pagesByType := make(map[string][]string)
+	for _, page := range cfg.pages {
+		for _, schemaType := range page.SchemaTypes {
+			pagesByType[schemaType] = append(pagesByType[schemaType], page.URL)
+		}
+	}
+
+	result := []schemaTypeUsage{}
+	for schemaType, pageURLs := range pagesByType {
+		slices.Sort(pageURLs)
+		result = append(result, schemaTypeUsage{SchemaType: schemaType, PageURLs: pageURLs})
+	}
+
+	slices.SortFunc(result, func(a, b schemaTypeUsage) int {
+		return cmp.Or(cmp.Compare(len(b.PageURLs), len(a.PageURLs)), cmp.Compare(a.SchemaType, b.SchemaType))
+	})
+	return result
+}
+
+// jsonLDErrors lists the JSON-LD blocks that failed to parse, sorted by page
+// and then by block.
+func (cfg *config) jsonLDErrors() []pageJSONLDError {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	result := []pageJSONLDError{}
+	for _, page := range cfg.pages {
+		for _, jsonLDErr := range page.JSONLDErrors {
+			result = append(result, pageJSONLDError{PageURL: page.URL, Block: jsonLDErr.Block, Error: jsonLDErr.Error})
+		}
+	}
+
+	slices.SortFunc(result, func(a, b pageJSONLDError) int {
+		return cmp.Or(cmp.Compare(a.PageURL, b.PageURL), cmp.Compare(a.Block, b.Block))
+	})
+	return result
+}
+
+func writeSchemaTypesReport(usages []schemaTypeUsage, filename string) error {
+	rows := [][]string{}
+	for _, usage := range usages {
+		rows = append(rows, []string{
+			usage.SchemaType,
+			strconv.Itoa(len(usage.PageURLs)),
+			strings.Join(usage.PageURLs, ","),
+		})
+	}
+	return writeCSV(filename, []string{"schema_type", "page_count", "page_urls"}, rows)
+}
+
+func writeJSONLDErrorsReport(errors []pageJSONLDError, filename string) error {
+	rows := [][]string{}
+	for _, jsonLDErr := range errors {
+		rows = append(rows, []string{
+			jsonLDErr.PageURL,
+			strconv.Itoa(jsonLDErr.Block),
+			jsonLDErr.Error,
+		})
+	}
+	return writeCSV(filename, []string{"page_url", "block", "error"}, rows)
+}
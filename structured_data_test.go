@@ -0,0 +1,155 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStructuredDataFromHTML(t *testing.T) {
+	html := `<html><head>
+		<meta property="og:title" content="Red shoes">
+		<meta property="og:image" content="https://example.com/shoes.jpg">
+		<meta property="og:image" content="https://example.com/shoes-2.jpg">
+		<meta name="twitter:card" content="summary_large_image">
+		<meta property="twitter:site" content="@shop">
+		<meta name="description" content="Not structured data">
+		<script type="application/ld+json">{"@context": "https://schema.org", "@type": "Product", "name": "Red shoes"}</script>
+		<script type="application/ld+json">
+			{"@context": "https://schema.org", "@graph": [
+				{"@type": "WebPage", "@id": "#page"},
+				{"@type": ["Organization", "Brand"], "name": "Shop"}
+			]}
+		</script>
+		<script type="application/ld+json">{"@type": "Offer",}</script>
+		<script type="Application/LD+JSON">[{"@type": "BreadcrumbList"}, {"@type": "Product"}]</script>
+		<script type="application/ld+json">"just a string"</script>
+		<script type="application/json">{"@type": "Ignored"}</script>
+	</head><body></body></html>`
+	page, err := parsePage(html, "https://example.com/shoes")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	actual := structuredDataFromDocument(page.Doc)
+
+	expectedOpenGraph := map[string]string{
+		"og:title": "Red shoes",
+		"og:image": "https://example.com/shoes.jpg",
+	}
+	if !reflect.DeepEqual(actual.OpenGraph, expectedOpenGraph) {
+		t.Errorf("Expected OpenGraph: %v\nActual: %v", expectedOpenGraph, actual.OpenGraph)
+	}
+	expectedTwitterCard := map[string]string{
+		"twitter:card": "summary_large_image",
+		"twitter:site": "@shop",
+	}
+	if !reflect.DeepEqual(actual.TwitterCard, expectedTwitterCard) {
+		t.Errorf("Expected TwitterCard: %v\nActual: %v", expectedTwitterCard, actual.TwitterCard)
+	}
+	expectedTypes := []string{"Brand", "BreadcrumbList", "Organization", "Product", "WebPage"}
+	if !reflect.DeepEqual(actual.SchemaTypes, expectedTypes) {
+		t.Errorf("Expected schema types: %v\nActual: %v", expectedTypes, actual.SchemaTypes)
+	}
+	if len(actual.JSONLD) != 5 || actual.JSONLD[0]["name"] != "Red shoes" || actual.JSONLD[1]["@id"] != "#page" {
+		t.Errorf("Expected 5 JSON-LD nodes with @graph flattened, got %v", actual.JSONLD)
+	}
+	if len(actual.JSONLDErrors) != 2 || actual.JSONLDErrors[0].Block != 3 || actual.JSONLDErrors[1].Block != 5 {
+		t.Errorf("Expected blocks 3 and 5 to fail to parse, got %+v", actual.JSONLDErrors)
+	}
+}
+
+func TestParseJSONLD(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		expectedTypes []string
+		expectError   bool
+	}{
+		{
+			name:          "single node",
+			text:          `{"@type": "Article"}`,
+			expectedTypes: []string{"Article"},
+		},
+		{
+			name:          "array of nodes, non-objects skipped",
+			text:          `[{"@type": "Event"}, 42, {"@type": "Place"}]`,
+			expectedTypes: []string{"Event", "Place"},
+		},
+		{
+			name:          "graph inside an array",
+			text:          `[{"@graph": [{"@type": "Person"}, {"@type": "WebSite"}]}]`,
+			expectedTypes: []string{"Person", "WebSite"},
+		},
+		{
+			name:          "node without a type",
+			text:          `{"name": "Untyped"}`,
+			expectedTypes: []string{},
+		},
+		{
+			name:        "invalid JSON",
+			text:        `{"@type": "Article"`,
+			expectError: true,
+		},
+		{
+			name:        "not an object",
+			text:        `true`,
+			expectError: true,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			nodes, err := parseJSONLD(tc.text)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Test %v - %s\nExpected an error, got nodes %v", i+1, tc.name, nodes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+			}
+			actual := []string{}
+			for _, node := range nodes {
+				actual = append(actual, jsonLDTypes(node)...)
+			}
+			if !reflect.DeepEqual(tc.expectedTypes, actual) {
+				t.Errorf("Test %v - %s\nExpected: %v\nActual: %v", i+1, tc.name, tc.expectedTypes, actual)
+			}
+		})
+	}
+}
+
+func TestStructuredDataReports(t *testing.T) {
+	cfg, err := configure("https://example.com", 1, 10, -1, true, defaultFetcherSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.pages["example.com/b"] = PageData{
+		URL:            "https://example.com/b",
+		StructuredData: StructuredData{SchemaTypes: []string{"Product", "WebPage"}},
+	}
+	cfg.pages["example.com/a"] = PageData{
+		URL: "https://example.com/a",
+		StructuredData: StructuredData{
+			SchemaTypes:  []string{"WebPage"},
+			JSONLDErrors: []jsonLDError{{Block: 2, Error: "unexpected end of JSON input"}, {Block: 1, Error: "bad"}},
+		},
+	}
+	cfg.pages["example.com/c"] = PageData{URL: "https://example.com/c"}
+
+	expectedTypes := []schemaTypeUsage{
+		{SchemaType: "WebPage", PageURLs: []string{"https://example.com/a", "https://example.com/b"}},
+		{SchemaType: "Product", PageURLs: []string{"https://example.com/b"}},
+	}
+	if actual := cfg.schemaTypes(); !reflect.DeepEqual(expectedTypes, actual) {
+		t.Errorf("Expected: %+v\nActual: %+v", expectedTypes, actual)
+	}
+
+	expectedErrors := []pageJSONLDError{
+		{PageURL: "https://example.com/a", Block: 1, Error: "bad"},
+		{PageURL: "https://example.com/a", Block: 2, Error: "unexpected end of JSON input"},
+	}
+	if actual := cfg.jsonLDErrors(); !reflect.DeepEqual(expectedErrors, actual) {
+		t.Errorf("Expected: %+v\nActual: %+v", expectedErrors, actual)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Heuristics that flag a URL as part of a crawler trap, such as a calendar with
+// a link to every next month or faceted navigation with every filter combination.
+const (
+	trapPathDepth         = "path_depth"
+	trapRepeatingSegments = "repeating_segments"
+	trapNumericPattern    = "numeric_pattern"
+	trapQueryParams       = "query_params"
+	trapQueryVariants     = "query_variants"
+)
+
+// trapSettings are the limits past which a URL is a trap. 0 turns a check off.
+type trapSettings struct {
+	maxPathSegments   int
+	maxSegmentRepeats int
+	maxPatternURLs    int
+	maxQueryParams    int
+	maxQueryVariants  int
+}
+
+func defaultTrapSettings() trapSettings {
+	return trapSettings{
+		maxPathSegments:   20,
+		maxSegmentRepeats: 3,
+		maxPatternURLs:    500,
+		maxQueryParams:    10,
+		maxQueryVariants:  100,
+	}
+}
+
+// trapURL is a URL that wasn't crawled because it looked like a trap. URLs
+// caught by the same heuristic for the same reason share a pattern.
+type trapURL struct {
+	URL     string `json:"url"`
+	Reason  string `json:"reason"`
+	Pattern string `json:"pattern"`
+}
+
+// trapDetector checks normalized URLs before they are queued. It isn't safe
+// for concurrent use; the crawl calls it with cfg.mu held.
+type trapDetector struct {
+	settings      trapSettings
+	patterns      map[string]int
+	queryVariants map[string]int
+	found         map[string]trapURL
+}
+
+func newTrapDetector(settings trapSettings) *trapDetector {
+	return &trapDetector{
+		settings:      settings,
+		patterns:      make(map[string]int),
+		queryVariants: make(map[string]int),
+		found:         make(map[string]trapURL),
+	}
+}
+
+var digitsRegex = regexp.MustCompile(`[0-9]+`)
+
+// numericPattern replaces every run of digits after the host with #, so
+// calendar/2024/05 and calendar/2031/11 share the pattern calendar/#/#.
+func numericPattern(normalizedURL string) string {
+	hostEnd := strings.IndexAny(normalizedURL, "/?")
+	if hostEnd == -1 {
+		return normalizedURL
+	}
+	return normalizedURL[:hostEnd] + digitsRegex.ReplaceAllString(normalizedURL[hostEnd:], "#")
+}
+
+// splitNormalizedURL splits a normalized URL into its host and path, the path's
+// segments and the query, if the normalization rules kept it.
+func splitNormalizedURL(normalizedURL string) (hostPath string, segments []string, query string) {
+	hostPath, query, _ = strings.Cut(normalizedURL, "?")
+	_, urlPath, _ := strings.Cut(hostPath, "/")
+	for _, segment := range strings.Split(urlPath, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return hostPath, segments, query
+}
+
+// check returns the trap a new URL falls into, or an empty trapURL if it
+// looks fine.
+func (d *trapDetector) check(normalizedURL string) trapURL {
+	hostPath, segments, query := splitNormalizedURL(normalizedURL)
+	trap := func(reason, pattern string) trapURL {
+		return trapURL{URL: normalizedURL, Reason: reason, Pattern: pattern}
+	}
+
+	if d.settings.maxPathSegments > 0 && len(segments) > d.settings.maxPathSegments {
+		host, _, _ := strings.Cut(hostPath, "/")
+		return trap(trapPathDepth, host+"/"+strings.Join(segments[:min(len(segments), 3)], "/")+"/...")
+	}
+	if d.settings.maxSegmentRepeats > 0 {
+		repeats := make(map[string]int)
+		for _, segment := range segments {
+			repeats[segment]++
+			if repeats[segment] > d.settings.maxSegmentRepeats {
+				return trap(trapRepeatingSegments, "/"+segment+"/")
+			}
+		}
+	}
+	if query != "" {
+		if d.settings.maxQueryParams > 0 && strings.Count(query, "&")+1 > d.settings.maxQueryParams {
+			return trap(trapQueryParams, hostPath)
+		}
+		if d.settings.maxQueryVariants > 0 && d.queryVariants[hostPath] >= d.settings.maxQueryVariants {
+			return trap(trapQueryVariants, hostPath)
+		}
+	}
+	if pattern := numericPattern(normalizedURL); pattern != normalizedURL &&
+		d.settings.maxPatternURLs > 0 && d.patterns[pattern] >= d.settings.maxPatternURLs {
+		return trap(trapNumericPattern, pattern)
+	}
+	return trapURL{}
+}
+
+// observe counts a URL that is going to be crawled towards the pattern limits.
+func (d *trapDetector) observe(normalizedURL string) {
+	hostPath, _, query := splitNormalizedURL(normalizedURL)
+	if query != "" {
+		d.queryVariants[hostPath]++
+	}
+	if pattern := numericPattern(normalizedURL); pattern != normalizedURL {
+		d.patterns[pattern]++
+	}
+}
+
+// trapSummary is the number of trap URLs caught for one reason and pattern.
+type trapSummary struct {
+	Reason  string
+	Pattern string
+	Count   int
+	Example string
+}
+
+// summary groups the trap URLs found, largest groups first.
+func (d *trapDetector) summary() []trapSummary {
+	groups := make(map[trapURL]*trapSummary)
+	for _, trap := range d.found {
+		key := trapURL{Reason: trap.Reason, Pattern: trap.Pattern}
+		group, ok := groups[key]
+		if !ok {
+			group = &trapSummary{Reason: trap.Reason, Pattern: trap.Pattern, Example: trap.URL}
+			groups[key] = group
+		}
+		group.Count++
+		group.Example = min(group.Example, trap.URL)
+	}
+
+	result := []trapSummary{}
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	slices.SortFunc(result, func(a, b trapSummary) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Reason, b.Reason), cmp.Compare(a.Pattern, b.Pattern))
+	})
+	return result
+}
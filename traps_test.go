@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTrapDetectorCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		seen          []string
+		normalizedURL string
+		expected      trapURL
+	}{
+		{
+			name:          "ordinary URL",
+			normalizedURL: "example.com/blog/post",
+		},
+		{
+			name:          "too many path segments",
+			normalizedURL: "example.com/a/b/c/d/e/f",
+			expected:      trapURL{URL: "example.com/a/b/c/d/e/f", Reason: trapPathDepth, Pattern: "example.com/a/b/c/..."},
+		},
+		{
+			name:          "repeating segment",
+			normalizedURL: "example.com/x/y/x/y/x",
+			expected:      trapURL{URL: "example.com/x/y/x/y/x", Reason: trapRepeatingSegments, Pattern: "/x/"},
+		},
+		{
+			name:          "numeric pattern under the limit",
+			seen:          []string{"example.com/calendar/2024/1", "example.com/calendar/2024/2"},
+			normalizedURL: "example.com/calendar/2024/3",
+		},
+		{
+			name:          "numeric pattern over the limit",
+			seen:          []string{"example.com/calendar/2024/1", "example.com/calendar/2024/2", "example.com/calendar/2024/3"},
+			normalizedURL: "example.com/calendar/2025/12",
+			expected:      trapURL{URL: "example.com/calendar/2025/12", Reason: trapNumericPattern, Pattern: "example.com/calendar/#/#"},
+		},
+		{
+			name:          "too many query parameters",
+			normalizedURL: "example.com/shop?a=1&b=2&c=3&d=4",
+			expected:      trapURL{URL: "example.com/shop?a=1&b=2&c=3&d=4", Reason: trapQueryParams, Pattern: "example.com/shop"},
+		},
+		{
+			name:          "too many query variants",
+			seen:          []string{"example.com/shop?color=red", "example.com/shop?size=l", "example.com/shop?color=red&size=l"},
+			normalizedURL: "example.com/shop?color=blue",
+			expected:      trapURL{URL: "example.com/shop?color=blue", Reason: trapQueryVariants, Pattern: "example.com/shop"},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newTrapDetector(trapSettings{
+				maxPathSegments:   5,
+				maxSegmentRepeats: 2,
+				maxPatternURLs:    3,
+				maxQueryParams:    3,
+				maxQueryVariants:  3,
+			})
+			for _, normalizedURL := range tc.seen {
+				d.observe(normalizedURL)
+			}
+			actual := d.check(tc.normalizedURL)
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Test %v - %s\nExpected: %+v\nActual: %+v", i+1, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCrawlStopsAtTraps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>")
+		if month, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/calendar/")); err == nil {
+			fmt.Fprintf(w, `<a href="/calendar/%d">Next month</a>`, month+1)
+		} else {
+			fmt.Fprint(w, `<a href="/calendar/1">Calendar</a><a href="/loop/">Loop</a>`)
+		}
+		if strings.HasPrefix(r.URL.Path, "/loop/") {
+			fmt.Fprint(w, `<a href="loop/">Deeper</a>`)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	defer server.Close()
+
+	cfg, err := configure(server.URL, 2, 1000, -1, true, testFetcherSettings())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	settings := defaultTrapSettings()
+	settings.maxPatternURLs = 5
+	cfg.traps = newTrapDetector(settings)
+	cfg.enqueue(server.URL+"/", discoveredByLink, 0)
+	cfg.crawl(context.Background())
+
+	host := cfg.baseURL.Hostname()
+	expected := []trapSummary{
+		{Reason: trapNumericPattern, Pattern: host + "/calendar/#", Count: 1, Example: host + "/calendar/6"},
+		{Reason: trapRepeatingSegments, Pattern: "/loop/", Count: 1, Example: host + "/loop/loop/loop/loop"},
+	}
+	actual := cfg.traps.summary()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %+v\nActual: %+v", expected, actual)
+	}
+	if len(cfg.pages) != 9 {
+		t.Errorf("Expected 9 pages crawled, got %d", len(cfg.pages))
+	}
+}
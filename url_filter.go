@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Kinds of include and exclude rules.
+const (
+	urlRulePrefix = "prefix"
+	urlRuleGlob   = "glob"
+	urlRuleRegex  = "regex"
+)
+
+// urlRule matches URLs on the crawled site by path. Prefix and glob rules look
+// at the path only, regular expressions at the path and query.
+type urlRule struct {
+	kind    string
+	pattern string
+	re      *regexp.Regexp
+}
+
+// parseURLRule reads a rule written as "prefix:/blog/", "glob:/blog/*/comments"
+// or "regex:^/search". Without a kind, the rule is a path prefix. In a glob, *
+// stays within one path segment while a ** segment matches any number of them.
+func parseURLRule(s string) (urlRule, error) {
+	kind, pattern, found := strings.Cut(s, ":")
+	if !found {
+		kind, pattern = urlRulePrefix, s
+	}
+	rule := urlRule{kind: kind, pattern: pattern}
+	switch kind {
+	case urlRulePrefix:
+	case urlRuleGlob:
+		if _, err := path.Match(pattern, ""); err != nil {
+			return urlRule{}, fmt.Errorf("invalid glob %q: %v", pattern, err)
+		}
+	case urlRuleRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return urlRule{}, fmt.Errorf("invalid regular expression %q: %v", pattern, err)
+		}
+		rule.re = re
+	default:
+		return urlRule{}, fmt.Errorf("unknown rule kind %q, want prefix, glob or regex", kind)
+	}
+	return rule, nil
+}
+
+func (r urlRule) String() string {
+	return r.kind + ":" + r.pattern
+}
+
+func (r urlRule) matches(u *url.URL) bool {
+	switch r.kind {
+	case urlRuleGlob:
+		return matchGlobSegments(strings.Split(r.pattern, "/"), strings.Split(u.Path, "/"))
+	case urlRuleRegex:
+		target := u.Path
+		if u.RawQuery != "" {
+			target += "?" + u.RawQuery
+		}
+		return r.re.MatchString(target)
+	}
+	return strings.HasPrefix(u.Path, r.pattern)
+}
+
+// matchGlobSegments matches path segments one by one with path.Match, letting
+// a ** segment stand for any number of segments, including none.
+func matchGlobSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(segments); i >= 0; i-- {
+				if matchGlobSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// urlRules is a repeatable flag.Value collecting rules.
+type urlRules []urlRule
+
+func (rules *urlRules) String() string {
+	if rules == nil {
+		return ""
+	}
+	parts := []string{}
+	for _, rule := range *rules {
+		parts = append(parts, rule.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+func (rules *urlRules) Set(s string) error {
+	rule, err := parseURLRule(s)
+	if err != nil {
+		return err
+	}
+	*rules = append(*rules, rule)
+	return nil
+}
+
+// urlFilter scopes a crawl. A URL is crawled if no exclude rule matches it and,
+// when there are include rules, at least one of them does.
+type urlFilter struct {
+	includes urlRules
+	excludes urlRules
+}
+
+// excludedBy returns why a URL is out of scope, or "" if it isn't.
+func (f urlFilter) excludedBy(u *url.URL) string {
+	for _, rule := range f.excludes {
+		if rule.matches(u) {
+			return "excluded by -exclude " + rule.String()
+		}
+	}
+	if len(f.includes) == 0 {
+		return ""
+	}
+	for _, rule := range f.includes {
+		if rule.matches(u) {
+			return ""
+		}
+	}
+	return "not matched by any -include rule"
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestURLFilter(t *testing.T) {
+	tests := []struct {
+		name          string
+		includes      []string
+		excludes      []string
+		inputURL      string
+		expected      string
+		errorContains string
+	}{
+		{
+			name:     "no rules",
+			inputURL: "https://example.com/anything",
+			expected: "",
+		},
+		{
+			name:     "bare path is a prefix",
+			includes: []string{"/blog/"},
+			inputURL: "https://example.com/blog/post",
+			expected: "",
+		},
+		{
+			name:     "not matched by include",
+			includes: []string{"prefix:/blog/"},
+			inputURL: "https://example.com/shop/item",
+			expected: "not matched by any -include rule",
+		},
+		{
+			name:     "exclude wins over include",
+			includes: []string{"/blog/"},
+			excludes: []string{"glob:/blog/*/comments"},
+			inputURL: "https://example.com/blog/post/comments",
+			expected: "excluded by -exclude glob:/blog/*/comments",
+		},
+		{
+			name:     "glob star stays within a segment",
+			excludes: []string{"glob:/blog/*/comments"},
+			inputURL: "https://example.com/blog/2024/post/comments",
+			expected: "",
+		},
+		{
+			name:     "glob double star spans segments",
+			excludes: []string{"glob:/blog/**/comments"},
+			inputURL: "https://example.com/blog/2024/post/comments",
+			expected: "excluded by -exclude glob:/blog/**/comments",
+		},
+		{
+			name:     "glob double star matches no segments",
+			excludes: []string{"glob:/blog/**/comments"},
+			inputURL: "https://example.com/blog/comments",
+			expected: "excluded by -exclude glob:/blog/**/comments",
+		},
+		{
+			name:     "glob double star covers a subtree",
+			includes: []string{"glob:/docs/**"},
+			inputURL: "https://example.com/blog/docs/page",
+			expected: "not matched by any -include rule",
+		},
+		{
+			name:     "glob double star at the end",
+			includes: []string{"glob:/docs/**"},
+			inputURL: "https://example.com/docs/v2/api/page",
+			expected: "",
+		},
+		{
+			name:     "regex sees the query",
+			excludes: []string{"regex:^/search\\?"},
+			inputURL: "https://example.com/search?q=shoes",
+			expected: `excluded by -exclude regex:^/search\?`,
+		},
+		{
+			name:          "invalid regex",
+			excludes:      []string{"regex:(unclosed"},
+			errorContains: "invalid regular expression",
+		},
+		{
+			name:          "unknown kind",
+			excludes:      []string{"suffix:.pdf"},
+			errorContains: "unknown rule kind",
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filter := urlFilter{}
+			for _, rule := range tc.includes {
+				if err := filter.includes.Set(rule); err != nil {
+					t.Fatalf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+				}
+			}
+			for _, rule := range tc.excludes {
+				err := filter.excludes.Set(rule)
+				if err != nil && tc.errorContains != "" {
+					if !strings.Contains(err.Error(), tc.errorContains) {
+						t.Errorf("Test %v - %s\nExpected error containing %q, got %v", i+1, tc.name, tc.errorContains, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Test %v - %s\nUnexpected error: %v", i+1, tc.name, err)
+				}
+			}
+			if tc.errorContains != "" {
+				t.Fatalf("Test %v - %s\nExpected an error containing %q", i+1, tc.name, tc.errorContains)
+			}
+
+			parsedURL, err := url.Parse(tc.inputURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := filter.excludedBy(parsedURL)
+			if actual != tc.expected {
+				t.Errorf("Test %v - %s\nExpected: %q\nActual: %q", i+1, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCrawlExcluded(t *testing.T) {
+	server, requested := newTestSite(t)
+	cfg, err := configure(server.URL, 2, 100, -1, true, testFetcherSettings())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg.filter.excludes.Set("/b")
+	cfg.filter.excludes.Set("regex:2$")
+
+	cfg.enqueue(server.URL+"/", discoveredByLink, 0)
+	cfg.crawl(context.Background())
+
+	crawled := requested()
+	slices.Sort(crawled)
+	if expected := []string{"/", "/a", "/a1"}; !reflect.DeepEqual(crawled, expected) {
+		t.Errorf("Expected crawled: %v\nActual: %v", expected, crawled)
+	}
+
+	host := cfg.baseURL.Hostname()
+	expected := map[string]string{
+		host + "/b":  "excluded by -exclude prefix:/b",
+		host + "/a2": "excluded by -exclude regex:2$",
+	}
+	actual := map[string]string{}
+	for normalizedURL, page := range cfg.excluded {
+		actual[normalizedURL] = page.SkipReason
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected excluded: %v\nActual: %v", expected, actual)
+	}
+}